@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v53/github"
+
+	"github.com/inburst/prty/datasource"
+)
+
+const (
+	EventAdded   = "added"
+	EventUpdated = "updated"
+	EventRemoved = "removed"
+	EventStatus  = "status"
+	EventRate    = "rate"
+)
+
+// events queued per subscriber before it starts missing them
+const subscriberBuffer = 64
+
+// idle connections are dropped by some proxies, this keeps them open
+var keepAliveInterval = 30 * time.Second
+
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// Broadcaster fans datasource events out to every subscriber. A subscriber
+// that falls behind misses events rather than holding up the datasource.
+type Broadcaster struct {
+	mutex       sync.Mutex
+	subscribers map[chan *Event]bool
+	// PRs announced so far, to tell additions from updates
+	known map[string]bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: map[chan *Event]bool{},
+		known:       map[string]bool{},
+	}
+}
+
+func (b *Broadcaster) Subscribe() chan *Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ch := make(chan *Event, subscriberBuffer)
+	b.subscribers[ch] = true
+	return ch
+}
+
+func (b *Broadcaster) Unsubscribe(ch chan *Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.subscribers, ch)
+}
+
+func (b *Broadcaster) numSubscribers() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *Broadcaster) publish(name string, data interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.publishLocked(&Event{Name: name, Data: data})
+}
+
+func (b *Broadcaster) publishLocked(e *Event) {
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Sends the PR as added, updated or removed
+func (b *Broadcaster) PublishPull(pr *datasource.PullRequest) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	name := EventUpdated
+	if pr.IsClosed {
+		name = EventRemoved
+		delete(b.known, pr.ID)
+	} else if !b.known[pr.ID] {
+		name = EventAdded
+		b.known[pr.ID] = true
+	}
+	b.publishLocked(&Event{Name: name, Data: newPullResponse(pr)})
+}
+
+func (b *Broadcaster) PublishStatus(message string) {
+	b.publish(EventStatus, message)
+}
+
+func (b *Broadcaster) PublishRate(rate github.Rate) {
+	b.publish(EventRate, newRateResponse(&rate))
+}
+
+// GET /api/events streams every event to the client as server-sent events
+// until it disconnects
+func (a *API) HandleEvents(c *gin.Context) {
+	ch := a.Events.Subscribe()
+	defer a.Events.Unsubscribe(ch)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e := <-ch:
+			c.SSEvent(e.Name, e.Data)
+		case <-keepAlive.C:
+			c.SSEvent("ping", "")
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
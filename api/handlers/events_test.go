@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/datasource"
+)
+
+var _ = Describe("Broadcaster", func() {
+	var b *Broadcaster
+
+	BeforeEach(func() {
+		b = NewBroadcaster()
+	})
+
+	It("should send every event to every subscriber", func() {
+		first, second := b.Subscribe(), b.Subscribe()
+		b.PublishStatus("refreshing")
+		Expect((<-first).Data).To(Equal("refreshing"))
+		Expect((<-second).Data).To(Equal("refreshing"))
+	})
+
+	It("should tell additions, updates and removals apart", func() {
+		ch := b.Subscribe()
+		pr := &datasource.PullRequest{ID: "a"}
+		b.PublishPull(pr)
+		b.PublishPull(pr)
+		b.PublishPull(&datasource.PullRequest{ID: "a", IsClosed: true})
+		Expect((<-ch).Name).To(Equal(EventAdded))
+		Expect((<-ch).Name).To(Equal(EventUpdated))
+		Expect((<-ch).Name).To(Equal(EventRemoved))
+	})
+
+	It("should drop events for subscribers that fall behind", func() {
+		ch := b.Subscribe()
+		for i := 0; i < subscriberBuffer+10; i++ {
+			b.PublishStatus("status")
+		}
+		Expect(ch).To(HaveLen(subscriberBuffer))
+	})
+
+	It("should stop sending to unsubscribed channels", func() {
+		ch := b.Subscribe()
+		b.Unsubscribe(ch)
+		b.PublishStatus("status")
+		Expect(ch).To(BeEmpty())
+	})
+})
+
+var _ = Describe("HandleEvents", func() {
+	It("should stream published events", func() {
+		gin.SetMode(gin.TestMode)
+		api := &API{Events: NewBroadcaster()}
+		router := gin.New()
+		router.GET("/api/events", api.HandleEvents)
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/api/events")
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/event-stream"))
+
+		Eventually(api.Events.numSubscribers).Should(Equal(1))
+		api.Events.PublishPull(&datasource.PullRequest{ID: "a", Number: 4})
+
+		reader := bufio.NewReader(resp.Body)
+		lines := []string{}
+		for len(lines) < 2 {
+			line, err := reader.ReadString('\n')
+			Expect(err).To(BeNil())
+			if len(strings.TrimSpace(line)) > 0 {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		}
+		Expect(lines[0]).To(Equal("event:" + EventAdded))
+		Expect(lines[1]).To(ContainSubstring(`"Number":4`))
+	})
+})
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v53/github"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/stats"
+)
+
+// Source is the live data the api serves
+type Source interface {
+	GetPulls() []*datasource.PullRequest
+	RefreshData()
+	IsCurrentlyRefreshingData() bool
+	RateInfo() *github.Rate
+}
+
+// API serves ranked PRs from a source using the same tabs as the UI
+type API struct {
+	Source    Source
+	Tabs      []config.TabConfig
+	Events    *Broadcaster
+	LoadStats func() (*stats.Stats, error)
+}
+
+// pullResponse is a PR as listed in a tab, with the score of each feature
+// that went into its importance
+type pullResponse struct {
+	ID                string
+	Provider          string
+	OrgName           string
+	RepoName          string
+	Number            int
+	Title             string
+	URL               string
+	Author            string
+	Turn              string
+	IsDraft           bool
+	IsApproved        bool
+	ApprovalCount     int
+	RequiredApprovals int
+	ChecksState       string
+	Importance        float64
+	ImportanceLookup  map[string]float64
+	// the labels shown in the UI footer
+	Tags                        []datasource.Tag
+	FirstCommitTime             time.Time
+	BusinessTimeSinceLastCommit time.Duration
+}
+
+func newPullResponse(pr *datasource.PullRequest) *pullResponse {
+	return &pullResponse{
+		ID:                pr.ID,
+		Provider:          pr.Provider,
+		OrgName:           pr.OrgName,
+		RepoName:          pr.RepoName,
+		Number:            pr.Number,
+		Title:             pr.Title,
+		URL:               pr.URL,
+		Author:            pr.Author,
+		Turn:              pr.Turn,
+		IsDraft:           pr.IsDraft,
+		IsApproved:        pr.IsApproved,
+		ApprovalCount:     pr.ApprovalCount,
+		RequiredApprovals: pr.RequiredApprovals,
+		ChecksState:       pr.ChecksState,
+		Importance:        pr.Importance,
+		ImportanceLookup:  pr.ImportanceLookup,
+
+		Tags:                        pr.Tags(),
+		FirstCommitTime:             pr.FirstCommitTime,
+		BusinessTimeSinceLastCommit: pr.BusinessTimeSinceLastCommit,
+	}
+}
+
+// GET /api/tabs
+func (a *API) HandleListTabs(c *gin.Context) {
+	c.JSON(http.StatusOK, a.Tabs)
+}
+
+// GET /api/tabs/:tab/pulls
+func (a *API) HandleListPulls(c *gin.Context) {
+	name := c.Param("tab")
+	for _, tab := range a.Tabs {
+		if !strings.EqualFold(tab.Name, name) {
+			continue
+		}
+		pulls, err := datasource.FilterPulls(tab, a.Source.GetPulls())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response := []*pullResponse{}
+		for _, pr := range pulls {
+			response = append(response, newPullResponse(pr))
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "no tab named " + name})
+}
+
+// GET /api/pulls/*id, ids from gitlab contain a slash
+func (a *API) HandleGetPull(c *gin.Context) {
+	id := strings.TrimPrefix(c.Param("id"), "/")
+	for _, pr := range a.Source.GetPulls() {
+		if pr.ID == id {
+			c.JSON(http.StatusOK, pr)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "no PR with id " + id})
+}
+
+// POST /api/refresh starts a refresh unless one is already running
+func (a *API) HandleRefresh(c *gin.Context) {
+	if !a.Source.IsCurrentlyRefreshingData() {
+		go a.Source.RefreshData()
+	}
+	c.JSON(http.StatusAccepted, gin.H{"refreshing": true})
+}
+
+// GET /api/status
+func (a *API) HandleStatus(c *gin.Context) {
+	response := gin.H{
+		"refreshing": a.Source.IsCurrentlyRefreshingData(),
+		"pulls":      len(a.Source.GetPulls()),
+		"rate":       nil,
+	}
+	if rate := a.Source.RateInfo(); rate != nil {
+		response["rate"] = newRateResponse(rate)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/stats
+func (a *API) HandleStats(c *gin.Context) {
+	s, err := a.LoadStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func newRateResponse(rate *github.Rate) gin.H {
+	return gin.H{
+		"limit":     rate.Limit,
+		"remaining": rate.Remaining,
+		"reset":     rate.Reset.Time,
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/stats"
+)
+
+type fakeSource struct {
+	pulls      []*datasource.PullRequest
+	refreshed  chan bool
+	refreshing bool
+	rate       *github.Rate
+}
+
+func (f *fakeSource) GetPulls() []*datasource.PullRequest { return f.pulls }
+func (f *fakeSource) RefreshData()                        { f.refreshed <- true }
+func (f *fakeSource) IsCurrentlyRefreshingData() bool     { return f.refreshing }
+func (f *fakeSource) RateInfo() *github.Rate              { return f.rate }
+
+var _ = Describe("API", func() {
+	var source *fakeSource
+	var router *gin.Engine
+
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		source = &fakeSource{
+			pulls: []*datasource.PullRequest{
+				{ID: "a", Number: 1, IAmAuthor: true, Importance: 10},
+				{ID: "b", Number: 2, IAmAuthor: true, Importance: 50, ImportanceLookup: map[string]float64{"Teammate": 50}},
+				{ID: "gitlab/3", Number: 3},
+			},
+			refreshed: make(chan bool, 1),
+		}
+		api := &API{
+			Source: source,
+			Tabs:   []config.TabConfig{{Name: "Mine", Filter: "IAmAuthor"}},
+			LoadStats: func() (*stats.Stats, error) {
+				return &stats.Stats{LifetimePROpens: 7}, nil
+			},
+		}
+		router = gin.New()
+		router.GET("/api/tabs/:tab/pulls", api.HandleListPulls)
+		router.GET("/api/pulls/*id", api.HandleGetPull)
+		router.POST("/api/refresh", api.HandleRefresh)
+		router.GET("/api/status", api.HandleStatus)
+		router.GET("/api/stats", api.HandleStats)
+	})
+
+	Describe("HandleListPulls", func() {
+		It("should list the tab's PRs by importance with the breakdown", func() {
+			w := request("GET", "/api/tabs/mine/pulls")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			pulls := []*pullResponse{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &pulls)).To(Succeed())
+			Expect(pulls).To(HaveLen(2))
+			Expect(pulls[0].ID).To(Equal("b"))
+			Expect(pulls[0].ImportanceLookup).To(HaveKeyWithValue("Teammate", 50.0))
+		})
+
+		It("should 404 on an unknown tab", func() {
+			Expect(request("GET", "/api/tabs/nope/pulls").Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("HandleGetPull", func() {
+		It("should find PRs by ids containing a slash", func() {
+			w := request("GET", "/api/pulls/gitlab/3")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"Number":3`))
+		})
+
+		It("should 404 on an unknown id", func() {
+			Expect(request("GET", "/api/pulls/z").Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("HandleRefresh", func() {
+		It("should start a refresh", func() {
+			Expect(request("POST", "/api/refresh").Code).To(Equal(http.StatusAccepted))
+			Eventually(source.refreshed).Should(Receive())
+		})
+
+		It("should not start a second refresh", func() {
+			source.refreshing = true
+			Expect(request("POST", "/api/refresh").Code).To(Equal(http.StatusAccepted))
+			Consistently(source.refreshed).ShouldNot(Receive())
+		})
+	})
+
+	Describe("HandleStatus", func() {
+		It("should include the rate limit once known", func() {
+			Expect(request("GET", "/api/status").Body.String()).To(ContainSubstring(`"rate":null`))
+			source.rate = &github.Rate{Limit: 5000, Remaining: 4000}
+			Expect(request("GET", "/api/status").Body.String()).To(ContainSubstring(`"remaining":4000`))
+		})
+	})
+
+	Describe("HandleStats", func() {
+		It("should return the user's stats", func() {
+			w := request("GET", "/api/stats")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"LifetimePROpens":7`))
+		})
+	})
+})
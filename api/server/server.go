@@ -1,18 +1,39 @@
 package server
 
 import (
+	_ "embed"
+	"net/http"
+
 	"github.com/inburst/prty/api/handlers"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Listen() *gin.Engine {
+// a single page with no external dependencies so it works offline
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// Serves the api on addr until the server fails
+func Listen(addr string, api *handlers.API) error {
 	r := gin.Default()
-	registerHandlers(r)
-	r.Run()
-	return r
+	registerHandlers(r, api)
+	return r.Run(addr)
 }
 
-func registerHandlers(r *gin.Engine) {
+func registerHandlers(r *gin.Engine, api *handlers.API) {
+	r.GET("/", handleDashboard)
 	r.GET("/ping", handlers.HandlePing)
+
+	r.GET("/api/tabs", api.HandleListTabs)
+	r.GET("/api/tabs/:tab/pulls", api.HandleListPulls)
+	r.GET("/api/pulls/*id", api.HandleGetPull)
+	r.POST("/api/refresh", api.HandleRefresh)
+	r.GET("/api/status", api.HandleStatus)
+	r.GET("/api/events", api.HandleEvents)
+	r.GET("/api/stats", api.HandleStats)
+}
+
+func handleDashboard(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", dashboardHTML)
 }
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/api/handlers"
+)
+
+var _ = Describe("registerHandlers", func() {
+	It("should serve the embedded dashboard", func() {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		registerHandlers(r, &handlers.API{})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(w.Body.String()).To(ContainSubstring("/api/events"))
+	})
+})
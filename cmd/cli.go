@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/google/go-github/v53/github"
+
+	"github.com/inburst/prty/api/handlers"
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/logger"
+	"github.com/inburst/prty/tracking"
+	"github.com/inburst/prty/utils"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatTSV   = "tsv"
+)
+
+// subcommands run without the UI and exit when done
+var subcommands = map[string]func(args []string) error{
+	"list":    runList,
+	"next":    runNext,
+	"show":    runShow,
+	"refresh": runRefresh,
+	"serve":   runServe,
+	"train":   runTrain,
+}
+
+const usage = `usage: prty [command]
+
+With no command the interactive UI is started.
+
+commands:
+  list [--tab NAME] [--format table|json|tsv]  PRs in a tab, the first tab by default
+  next                                         URL of the top PR in Needs Attention
+  show [--format text|json] ORG/REPO#N         details of a cached PR
+  refresh                                      fetch every PR into the local cache
+  serve [--addr HOST:PORT] [--interval 10m]    serve the ranked PRs over http
+  train                                        fit the importance model on your history
+`
+
+// Runs the subcommand named by args[0] and exits. Returns when there is no
+// subcommand so the UI can start.
+func runSubcommand(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	if utils.Contains([]string{"help", "-h", "--help"}, args[0]) {
+		fmt.Print(usage)
+		os.Exit(0)
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	tracking.SendMetric("cli." + args[0])
+	if err := run(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// headlessDatasource drains the channels the UI would normally read.
+// Status messages go to the log and errors are kept so the command can fail.
+type headlessDatasource struct {
+	*datasource.Datasource
+	// receives every update when serving, nil otherwise
+	events *handlers.Broadcaster
+
+	mutex    sync.Mutex
+	errors   []string
+	rateInfo *github.Rate
+}
+
+func newHeadlessDatasource(c *config.Config, events *handlers.Broadcaster) (*headlessDatasource, error) {
+	if err := datasource.InitSharedClient(c); err != nil {
+		return nil, err
+	}
+	statusChan := make(chan string)
+	prUpdateChan := make(chan *datasource.PullRequest)
+	remainingRequestsChan := make(chan github.Rate)
+
+	h := &headlessDatasource{
+		Datasource: datasource.New(c),
+		events:     events,
+	}
+	h.SetStatusChan(statusChan)
+	h.SetPRUpdateChan(prUpdateChan)
+	h.SetRemainingRequestsChan(remainingRequestsChan)
+
+	go func() {
+		for message := range statusChan {
+			logger.Shared().Printf("status %s\n", message)
+			if h.events != nil {
+				h.events.PublishStatus(message)
+			}
+			if strings.HasPrefix(message, "ERROR") {
+				h.mutex.Lock()
+				h.errors = append(h.errors, message)
+				h.mutex.Unlock()
+			}
+		}
+	}()
+	go func() {
+		for pr := range prUpdateChan {
+			if h.events != nil {
+				h.events.PublishPull(pr)
+			}
+		}
+	}()
+	go func() {
+		for rate := range remainingRequestsChan {
+			rate := rate
+			h.mutex.Lock()
+			h.rateInfo = &rate
+			h.mutex.Unlock()
+			if h.events != nil {
+				h.events.PublishRate(rate)
+			}
+		}
+	}()
+
+	h.LoadLocalCache()
+	return h, nil
+}
+
+func (h *headlessDatasource) Errors() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.errors
+}
+
+// The most recent rate limit reported by the provider, nil before any call
+func (h *headlessDatasource) RateInfo() *github.Rate {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.rateInfo
+}
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	tab := flags.String("tab", "", "name of the tab to list, defaults to the first tab")
+	format := flags.String("format", formatTable, "output format, one of table, json or tsv")
+	flags.Parse(args)
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	tabs := datasource.TabConfigs(c)
+	if len(tabs) == 0 {
+		return errors.New("no tabs configured")
+	}
+	selected := tabs[0]
+	if len(*tab) > 0 {
+		found := false
+		names := []string{}
+		for _, t := range tabs {
+			names = append(names, t.Name)
+			if strings.EqualFold(t.Name, *tab) {
+				selected, found = t, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("no tab named %s, expected one of %s", *tab, strings.Join(names, ", "))
+		}
+	}
+
+	h, err := newHeadlessDatasource(c, nil)
+	if err != nil {
+		return err
+	}
+	return listPulls(os.Stdout, h, selected, *format)
+}
+
+func runNext(args []string) error {
+	flags := flag.NewFlagSet("next", flag.ExitOnError)
+	flags.Parse(args)
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	h, err := newHeadlessDatasource(c, nil)
+	if err != nil {
+		return err
+	}
+	return nextPull(os.Stdout, h)
+}
+
+func runShow(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: prty show [--format text|json] ORG/REPO#N")
+	}
+	org, repo, number, err := parsePullRef(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	h, err := newHeadlessDatasource(c, nil)
+	if err != nil {
+		return err
+	}
+	return showPull(os.Stdout, h, org, repo, number, *format)
+}
+
+// pullSource is the part of the datasource the read only subcommands use.
+// The PRs it returns are measured up to now.
+type pullSource interface {
+	GetPulls() []*datasource.PullRequest
+}
+
+func listPulls(w io.Writer, source pullSource, tab config.TabConfig, format string) error {
+	pulls, err := datasource.FilterPulls(tab, source.GetPulls())
+	if err != nil {
+		return err
+	}
+	return writePulls(w, format, pulls)
+}
+
+func nextPull(w io.Writer, source pullSource) error {
+	// always the built in tab, even when the default tabs are hidden
+	pulls, err := datasource.FilterPulls(datasource.DefaultTabs[0], source.GetPulls())
+	if err != nil {
+		return err
+	}
+	if len(pulls) == 0 {
+		return errors.New("nothing needs attention")
+	}
+	fmt.Fprintln(w, pulls[0].URL)
+	return nil
+}
+
+func showPull(w io.Writer, source pullSource, org string, repo string, number int, format string) error {
+	for _, pr := range source.GetPulls() {
+		if strings.EqualFold(pr.OrgName, org) && strings.EqualFold(pr.RepoName, repo) && pr.Number == number {
+			return writePull(w, format, pr)
+		}
+	}
+	return fmt.Errorf("%s/%s#%d is not in the cache, run prty refresh first", org, repo, number)
+}
+
+func runRefresh(args []string) error {
+	flags := flag.NewFlagSet("refresh", flag.ExitOnError)
+	flags.Parse(args)
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	h, err := newHeadlessDatasource(c, nil)
+	if err != nil {
+		return err
+	}
+	h.RefreshData()
+	if errs := h.Errors(); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	fmt.Printf("Refreshed %d PRs\n", h.NumPulls())
+	return nil
+}
+
+func runTrain(args []string) error {
+	trainModel()
+	return nil
+}
+
+// Splits "org/repo#12". Gitlab groups can be nested so the repo is whatever
+// follows the last slash.
+func parsePullRef(ref string) (string, string, int, error) {
+	hash := strings.LastIndex(ref, "#")
+	slash := strings.LastIndex(ref, "/")
+	if hash < 0 || slash <= 0 || slash > hash {
+		return "", "", 0, fmt.Errorf("expected ORG/REPO#N but got %s", ref)
+	}
+	number, err := strconv.Atoi(ref[hash+1:])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("expected ORG/REPO#N but got %s", ref)
+	}
+	return ref[:slash], ref[slash+1 : hash], number, nil
+}
+
+func pullRef(pr *datasource.PullRequest) string {
+	return fmt.Sprintf("%s/%s#%d", pr.OrgName, pr.RepoName, pr.Number)
+}
+
+// pullSummary is the subset of a PR written by list, the full PR is
+// available from show
+type pullSummary struct {
+	Ref               string
+	URL               string
+	Title             string
+	Author            string
+	Importance        float64
+	Turn              string
+	IsDraft           bool
+	IsApproved        bool
+	ApprovalCount     int
+	RequiredApprovals int
+	ChecksState       string
+}
+
+func summarize(pr *datasource.PullRequest) *pullSummary {
+	return &pullSummary{
+		Ref:               pullRef(pr),
+		URL:               pr.URL,
+		Title:             pr.Title,
+		Author:            pr.Author,
+		Importance:        pr.Importance,
+		Turn:              pr.Turn,
+		IsDraft:           pr.IsDraft,
+		IsApproved:        pr.IsApproved,
+		ApprovalCount:     pr.ApprovalCount,
+		RequiredApprovals: pr.RequiredApprovals,
+		ChecksState:       pr.ChecksState,
+	}
+}
+
+func writePulls(w io.Writer, format string, pulls []*datasource.PullRequest) error {
+	switch format {
+	case formatJSON:
+		summaries := []*pullSummary{}
+		for _, pr := range pulls {
+			summaries = append(summaries, summarize(pr))
+		}
+		data, err := json.MarshalIndent(summaries, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	case formatTSV:
+		// no header so each line can be cut and read as is
+		for _, pr := range pulls {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", pullRef(pr), formatImportance(pr), pr.Author, pr.Turn, pr.URL, strings.ReplaceAll(pr.Title, "\t", " "))
+		}
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "IMPORTANCE\tPR\tAUTHOR\tTURN\tTITLE")
+		for _, pr := range pulls {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", formatImportance(pr), pullRef(pr), pr.Author, pr.Turn, truncate(pr.Title, 60))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s, expected table, json or tsv", format)
+	}
+	return nil
+}
+
+func writePull(w io.Writer, format string, pr *datasource.PullRequest) error {
+	switch format {
+	case formatJSON:
+		data, err := json.MarshalIndent(pr, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\t%s\n", pullRef(pr), pr.Title)
+		fmt.Fprintf(tw, "URL\t%s\n", pr.URL)
+		fmt.Fprintf(tw, "Author\t%s\n", pr.Author)
+		fmt.Fprintf(tw, "Turn\t%s\n", pr.Turn)
+		fmt.Fprintf(tw, "Importance\t%s\n", formatImportance(pr))
+		fmt.Fprintf(tw, "Approvals\t%d/%d\n", pr.ApprovalCount, pr.RequiredApprovals)
+		fmt.Fprintf(tw, "Checks\t%s\n", pr.ChecksState)
+		fmt.Fprintf(tw, "Changes\t%d files +%d -%d\n", len(pr.ChangedFiles), pr.Additions, pr.Deletions)
+		fmt.Fprintf(tw, "Updated\t%s\n", pr.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s, expected text or json", format)
+	}
+	return nil
+}
+
+// My approved PRs score the largest float there is, which is no use printed
+func formatImportance(pr *datasource.PullRequest) string {
+	if _, ok := pr.ImportanceLookup["Ready"]; ok {
+		return "Ready"
+	}
+	return fmt.Sprintf("%.0f", pr.Importance)
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-1]) + "…"
+}
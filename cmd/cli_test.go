@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+)
+
+var _ = Describe("cli", func() {
+	Describe("parsePullRef", func() {
+		It("should split org, repo and number", func() {
+			org, repo, number, err := parsePullRef("inburst/prty#12")
+			Expect(err).To(BeNil())
+			Expect(org).To(Equal("inburst"))
+			Expect(repo).To(Equal("prty"))
+			Expect(number).To(Equal(12))
+		})
+
+		It("should keep nested gitlab groups in the org", func() {
+			org, repo, _, err := parsePullRef("group/sub/project#7")
+			Expect(err).To(BeNil())
+			Expect(org).To(Equal("group/sub"))
+			Expect(repo).To(Equal("project"))
+		})
+
+		It("should reject refs without a number", func() {
+			for _, ref := range []string{"inburst/prty", "prty#12", "inburst/prty#x", "inburst#1/prty"} {
+				_, _, _, err := parsePullRef(ref)
+				Expect(err).ToNot(BeNil(), ref)
+			}
+		})
+	})
+
+	Describe("writePulls", func() {
+		pulls := []*datasource.PullRequest{
+			{OrgName: "inburst", RepoName: "prty", Number: 12, Title: "Add\tthings", Author: "alice", URL: "https://github.com/inburst/prty/pull/12", Importance: 42.4, Turn: datasource.TurnReviewer},
+		}
+
+		It("should write one tab separated line per PR", func() {
+			out := bytes.Buffer{}
+			Expect(writePulls(&out, formatTSV, pulls)).To(Succeed())
+			Expect(out.String()).To(Equal("inburst/prty#12\t42\talice\treviewer\thttps://github.com/inburst/prty/pull/12\tAdd things\n"))
+		})
+
+		It("should write summaries as json", func() {
+			out := bytes.Buffer{}
+			Expect(writePulls(&out, formatJSON, pulls)).To(Succeed())
+			summaries := []*pullSummary{}
+			Expect(json.Unmarshal(out.Bytes(), &summaries)).To(Succeed())
+			Expect(summaries).To(HaveLen(1))
+			Expect(summaries[0].Ref).To(Equal("inburst/prty#12"))
+		})
+
+		It("should reject unknown formats", func() {
+			Expect(writePulls(&bytes.Buffer{}, "xml", pulls)).ToNot(Succeed())
+		})
+
+		It("should print Ready rather than the score of my approved PRs", func() {
+			ready := []*datasource.PullRequest{
+				{OrgName: "inburst", RepoName: "prty", Number: 13, Title: "Mine", Author: "me", Importance: math.MaxFloat64, ImportanceLookup: map[string]float64{"Ready": math.MaxFloat64}, Turn: datasource.TurnMerge},
+			}
+			tsv := bytes.Buffer{}
+			Expect(writePulls(&tsv, formatTSV, ready)).To(Succeed())
+			Expect(tsv.String()).To(HavePrefix("inburst/prty#13\tReady\tme\t"))
+
+			table := bytes.Buffer{}
+			Expect(writePulls(&table, formatTable, ready)).To(Succeed())
+			lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[1]).To(HavePrefix("Ready "))
+			Expect(len(lines[1])).To(BeNumerically("<", 80))
+
+			text := bytes.Buffer{}
+			Expect(writePull(&text, "text", ready[0])).To(Succeed())
+			Expect(text.String()).To(MatchRegexp(`(?m)^Importance +Ready$`))
+		})
+	})
+
+	// the cache holds PRs as they were scored when last fetched, the
+	// subcommands should measure them up to now
+	Describe("subcommands", func() {
+		var (
+			home     string
+			previous string
+			ds       *datasource.Datasource
+		)
+
+		BeforeEach(func() {
+			var err error
+			home, err = ioutil.TempDir("", "prty-cli")
+			Expect(err).NotTo(HaveOccurred())
+			previous = os.Getenv("HOME")
+			os.Setenv("HOME", home)
+			Expect(config.PrepApplicationCacheFolder()).To(Succeed())
+
+			now := time.Now()
+			cached := map[string]*datasource.PullRequest{
+				// needed attention when fetched but has been abandoned since
+				"stale": {
+					Provider: datasource.GithubProviderName, ID: "stale",
+					OrgName: "inburst", RepoName: "prty", Number: 1, URL: "https://github.com/inburst/prty/pull/1",
+					CreatedAt: now.AddDate(0, 0, -60), LastCommitTime: now.AddDate(0, 0, -30), LastCommentTime: now.AddDate(0, 0, -31),
+					HasChangesAfterLastComment: true, Importance: 500,
+				},
+				"fresh": {
+					Provider: datasource.GithubProviderName, ID: "fresh",
+					OrgName: "inburst", RepoName: "prty", Number: 2, URL: "https://github.com/inburst/prty/pull/2",
+					CreatedAt: now.Add(-3 * time.Hour), LastCommitTime: now.Add(-time.Hour), LastCommentTime: now.Add(-2 * time.Hour),
+					HasChangesAfterLastComment: true, Importance: 10,
+				},
+			}
+			data, err := json.Marshal(cached)
+			Expect(err).NotTo(HaveOccurred())
+			cachePath, err := config.GetPRCacheFilePath()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(cachePath, data, 0644)).To(Succeed())
+
+			ds = datasource.New(&config.Config{GithubUsername: "me"})
+			prUpdateChan := make(chan *datasource.PullRequest)
+			ds.SetStatusChan(make(chan string))
+			ds.SetPRUpdateChan(prUpdateChan)
+			ds.SetRemainingRequestsChan(make(chan github.Rate))
+			go func() {
+				for range prUpdateChan {
+				}
+			}()
+			ds.LoadLocalCache()
+		})
+
+		AfterEach(func() {
+			os.Setenv("HOME", previous)
+			os.RemoveAll(home)
+		})
+
+		It("should list what is in the tab now", func() {
+			out := bytes.Buffer{}
+			Expect(listPulls(&out, ds, datasource.DefaultTabs[0], formatJSON)).To(Succeed())
+			summaries := []*pullSummary{}
+			Expect(json.Unmarshal(out.Bytes(), &summaries)).To(Succeed())
+			Expect(summaries).To(HaveLen(1))
+			Expect(summaries[0].Ref).To(Equal("inburst/prty#2"))
+		})
+
+		It("should rank abandoned PRs by their current score", func() {
+			out := bytes.Buffer{}
+			Expect(listPulls(&out, ds, config.TabConfig{Name: "Everything", Filter: "!IsDraft"}, formatTSV)).To(Succeed())
+			lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+			Expect(lines).To(HaveLen(2))
+			Expect(string(lines[0])).To(HavePrefix("inburst/prty#2\t"))
+			Expect(string(lines[1])).To(HavePrefix("inburst/prty#1\t0\t"))
+		})
+
+		It("should skip PRs abandoned since they were fetched for next", func() {
+			out := bytes.Buffer{}
+			Expect(nextPull(&out, ds)).To(Succeed())
+			Expect(out.String()).To(Equal("https://github.com/inburst/prty/pull/2\n"))
+		})
+
+		It("should show times measured up to now", func() {
+			out := bytes.Buffer{}
+			Expect(showPull(&out, ds, "inburst", "prty", 1, formatJSON)).To(Succeed())
+			pr := &datasource.PullRequest{}
+			Expect(json.Unmarshal(out.Bytes(), pr)).To(Succeed())
+			Expect(pr.TimeSinceLastCommit).To(BeNumerically("~", 30*24*time.Hour, time.Minute))
+			Expect(pr.IsAbandoned).To(BeTrue())
+		})
+
+		It("should fail to show PRs that are not cached", func() {
+			Expect(showPull(&bytes.Buffer{}, ds, "inburst", "prty", 3, formatJSON)).NotTo(Succeed())
+		})
+	})
+})
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 	"time"
 
@@ -17,15 +18,21 @@ import (
 	"github.com/inburst/prty/tracking"
 	"github.com/inburst/prty/ui"
 	"github.com/inburst/prty/utils"
+	"github.com/inburst/prty/worktree"
 )
 
 var (
 	duration = time.Second * 10
 	interval = time.Second
+	// elapsed times are brought up to date this often between refreshes
+	rescoreInterval = time.Minute
 )
 
 type tickMsg time.Time
 
+// the path of a worktree that was checked out and should be opened
+type worktreeMsg string
+
 type model struct {
 	choices []string // items on the to-do list
 	//cursor   int              // which to-do list item our cursor is pointing at
@@ -37,45 +44,31 @@ type model struct {
 
 	cursor ui.CursorPos
 
-	nav        *ui.TabNav
-	views      []ui.PRViewData
-	footer     *ui.Footer
-	detailView *ui.PRDetail
-	statsView  *ui.Stats
+	nav         *ui.TabNav
+	views       []ui.PRViewData
+	footer      *ui.Footer
+	detailView  *ui.PRDetail
+	statsView   *ui.Stats
+	reviewInput *ui.ReviewInput
 
 	statusChan            chan string
 	statusMessage         string
 	remainingRequestsChan chan github.Rate
 	currentRateInfo       *github.Rate
-	totalPRs              int
 
 	ds           *datasource.Datasource
 	prUpdateChan chan *datasource.PullRequest
 
 	stats *stats.Stats
+
+	// a checked out worktree to open once the UI has stepped aside
+	launchDir string
 }
 
 var initialModel = model{
 	selectedTabIndex: 0,
-	tabNames: []string{
-		"Needs Attention",
-		"Team",
-		"Mine",
-		"Active",
-		"Bots",
-		"All",
-	},
-
-	nav: &ui.TabNav{},
-	views: []ui.PRViewData{
-		&ui.PriorityPRs{},
-		&ui.TeamPrs{},
-		&ui.MyPrs{},
-		&ui.ActivePRs{},
-		&ui.BotsPrs{},
-		&ui.AllPrs{},
-	},
-	footer: &ui.Footer{},
+	nav:              &ui.TabNav{},
+	footer:           &ui.Footer{},
 
 	statusChan:            make(chan string),
 	statusMessage:         "",
@@ -85,10 +78,16 @@ var initialModel = model{
 }
 
 func (m *model) Init() tea.Cmd {
+	// the UI is restarted after launching into a worktree, keep the state
+	if m.ds != nil {
+		return tick()
+	}
+
 	// these are pre-validated in checkConfiguration
 	c, _ := config.LoadConfig()
 	m.stats, _ = stats.LoadStats()
-	datasource.InitSharedClient(c.GithubAccessToken)
+	m.tabNames, m.views, _ = ui.BuildTabs(c)
+	datasource.InitSharedClient(c)
 
 	m.ds = datasource.New(c)
 	m.ds.SetStatusChan(m.statusChan)
@@ -100,6 +99,7 @@ func (m *model) Init() tea.Cmd {
 	go m.listenForRemainingRequests()
 
 	m.ds.LoadLocalCache()
+	go m.rescorePulls()
 
 	if c.RefreshOnStart {
 		go m.ds.RefreshData()
@@ -118,19 +118,30 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tickMsg:
 		return m, tick()
 
+	case worktreeMsg:
+		m.launchDir = string(msg)
+		return m, tea.Quit
+
 	case tea.KeyMsg:
+		// the review modal takes every key while it is open
+		if m.reviewInput != nil {
+			m.updateReviewInput(msg)
+			break
+		}
+		if m.detailView != nil && m.detailView.OnKey(msg.String()) {
+			break
+		}
+
 		switch msg.String() {
 
 		case "ctrl+c", "q":
+			m.ds.CancelRefresh()
 			return m, tea.Quit
 
 		case "r":
 			if m.IsViewingSecondary() {
 				break
 			}
-			for _, v := range m.views {
-				v.Clear()
-			}
 			m.refreshData()
 
 		case "s":
@@ -169,12 +180,32 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			v := m.views[m.cursor.X]
 			p := v.GetSelectedPull()
+			if p == nil {
+				break
+			}
 			m.detailView = &ui.PRDetail{
 				PR: p,
+				LoadFiles: func() ([]*datasource.FileDiff, error) {
+					return m.ds.GetFileDiffs(p)
+				},
 			}
 			tracking.SendMetric("view.detail")
 			m.stats.OnViewPR(p)
 
+		case "a", "x", "c":
+			if m.statsView != nil {
+				break
+			}
+			m.openReviewInput(msg.String())
+
+		case "w":
+			if m.statsView != nil {
+				break
+			}
+			if pr := m.selectedPull(); pr != nil {
+				return m, m.checkoutWorktree(pr)
+			}
+
 		case "esc":
 			m.detailView = nil
 			m.statsView = nil
@@ -223,8 +254,85 @@ func (m *model) sendSelectToActiveTab() {
 	m.ds.SaveToFile()
 }
 
+var reviewEventKeys = map[string]string{
+	"a": datasource.ReviewEventApprove,
+	"x": datasource.ReviewEventRequestChanges,
+	"c": datasource.ReviewEventComment,
+}
+
+// The PR open in the detail view or else the selected row
+func (m *model) selectedPull() *datasource.PullRequest {
+	if m.detailView != nil {
+		return m.detailView.PR
+	}
+	return m.views[m.cursor.X].GetSelectedPull()
+}
+
+func (m *model) openReviewInput(key string) {
+	pr := m.selectedPull()
+	if pr == nil {
+		return
+	}
+	m.reviewInput = &ui.ReviewInput{
+		PR:    pr,
+		Event: reviewEventKeys[key],
+	}
+	tracking.SendMetric("view.review")
+}
+
+func (m *model) updateReviewInput(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.reviewInput = nil
+	case tea.KeyBackspace:
+		m.reviewInput.Backspace()
+	case tea.KeySpace:
+		m.reviewInput.Insert([]rune{' '})
+	case tea.KeyRunes:
+		m.reviewInput.Insert(msg.Runes)
+	case tea.KeyEnter:
+		if !m.reviewInput.CanSubmit() {
+			break
+		}
+		input := m.reviewInput
+		m.reviewInput = nil
+		go m.submitReview(input.PR, input.Event, input.Value())
+	}
+}
+
+func (m *model) submitReview(pr *datasource.PullRequest, event string, body string) {
+	if err := m.ds.SubmitReview(pr, event, body); err != nil {
+		return
+	}
+	tracking.SendMetric("data.submitreview")
+	m.stats.OnReviewPR(pr, event)
+}
+
+// Checks out in the background and reports the path back to Update, which
+// steps the UI aside to open it
+func (m *model) checkoutWorktree(pr *datasource.PullRequest) tea.Cmd {
+	return func() tea.Msg {
+		m.statusChan <- fmt.Sprintf("checking out %s/%s #%d...", pr.OrgName, pr.RepoName, pr.Number)
+		c, _ := config.LoadConfig()
+		path, err := worktree.Checkout(c, pr)
+		if err != nil {
+			logger.Shared().Printf("error checking out worktree : %s\n", err)
+			m.statusChan <- fmt.Sprintf("error: %s", err)
+			return nil
+		}
+		tracking.SendMetric("data.checkout")
+		m.statusChan <- ""
+		return worktreeMsg(path)
+	}
+}
+
+func (m *model) rescorePulls() {
+	for range time.Tick(rescoreInterval) {
+		m.ds.RescorePulls()
+	}
+}
+
 func (m *model) refreshData() {
-	m.totalPRs = 0
 	go m.ds.RefreshData()
 }
 
@@ -242,16 +350,18 @@ func (m *model) View() string {
 	// Tab Nav
 	renderedPage.WriteString(m.nav.BuildView(width, navHeight, m.tabNames, m.cursor.X))
 	// Body View
-	if m.detailView != nil {
+	if m.reviewInput != nil {
+		renderedPage.WriteString(m.reviewInput.BuildView(width, bodyHeight))
+	} else if m.detailView != nil {
 		renderedPage.WriteString(m.detailView.BuildView(width, bodyHeight))
 	} else if m.statsView != nil {
 		renderedPage.WriteString(m.statsView.BuildView(width, bodyHeight))
 	} else {
 		v := m.views[m.cursor.X]
-		renderedPage.WriteString(ui.BuildPRView(v, width, bodyHeight, m.ds.IsCurrentlyRefreshingData()))
+		renderedPage.WriteString(ui.BuildPRView(v, width, bodyHeight, m.ds))
 	}
 	// Footer
-	renderedPage.WriteString(m.footer.BuildView(width, footerHeight, m.statusMessage, m.currentRateInfo, m.totalPRs))
+	renderedPage.WriteString(m.footer.BuildView(width, footerHeight, m.statusMessage, m.currentRateInfo, m.ds.NumPulls()))
 
 	return renderedPage.String()
 }
@@ -272,24 +382,69 @@ func (m *model) listenForRemainingRequests() {
 func (m *model) listenForPRChanges() {
 	for {
 		newPR := <-m.prUpdateChan
-		m.totalPRs++
 		for _, v := range m.views {
 			v.OnNewPullData(newPR)
 		}
+		// keep the open detail view current, e.g. after reviewing it
+		if m.detailView != nil && m.detailView.PR.ID == newPR.ID {
+			m.detailView.PR = newPR
+		}
+		if newPR.IsClosed {
+			go removeWorktree(newPR.ID)
+		}
+	}
+}
+
+func removeWorktree(prID string) {
+	if err := worktree.Remove(prID); err != nil {
+		logger.Shared().Printf("error removing worktree : %s\n", err)
 	}
 }
 
 func startUI() {
 	tracking.SendMetric("start")
 
-	p := tea.NewProgram(&initialModel)
-	// Use the full size of the terminal in its "alternate screen buffer"
-	p.EnterAltScreen()
-	defer p.ExitAltScreen()
+	for {
+		p := tea.NewProgram(&initialModel)
+		// Use the full size of the terminal in its "alternate screen buffer"
+		p.EnterAltScreen()
+		err := p.Start()
+		p.ExitAltScreen()
+
+		if err != nil {
+			fmt.Printf("Error starting UI : %s", err)
+			os.Exit(1)
+		}
+		if len(initialModel.launchDir) == 0 {
+			return
+		}
+		launchInto(initialModel.launchDir)
+		initialModel.launchDir = ""
+	}
+}
 
-	if err := p.Start(); err != nil {
-		fmt.Printf("Error starting UI : %s", err)
-		os.Exit(1)
+// Opens $EDITOR in dir, or a shell when it is not set, and waits for it to
+// exit before the UI comes back
+func launchInto(dir string) {
+	fmt.Printf("PR checked out at %s\n", dir)
+
+	var cmd *exec.Cmd
+	if editor := strings.Fields(os.Getenv("EDITOR")); len(editor) > 0 {
+		cmd = exec.Command(editor[0], append(editor[1:], dir)...)
+	} else {
+		shell := os.Getenv("SHELL")
+		if len(shell) == 0 {
+			shell = "/bin/sh"
+		}
+		fmt.Println("exit the shell to return to prty")
+		cmd = exec.Command(shell)
+	}
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		logger.Shared().Printf("error launching into %s : %s\n", dir, err)
 	}
 }
 
@@ -300,12 +455,13 @@ func handleArguments() {
 		fmt.Printf("version: %s\n", config.PRTYVersion)
 		os.Exit(0)
 	}
+	runSubcommand(args[1:])
 }
 
 func checkConfiguration() {
 	err := logger.InitializeLogger()
 	if err != nil {
-		fmt.Printf("Error initilizting logger %s\n%s", err)
+		fmt.Printf("Error initilizting logger %s\n", err)
 		os.Exit(1)
 	}
 
@@ -319,6 +475,13 @@ func checkConfiguration() {
 		os.Exit(1)
 	}
 
+	_, _, err = ui.BuildTabs(c)
+	if err != nil {
+		fmt.Printf("%s\n%s", err, moreInformationMessage)
+		tracking.SendMetric("confcheck.buildtabs.error")
+		os.Exit(1)
+	}
+
 	_, err = stats.LoadStats()
 	if err != nil {
 		fmt.Printf("%s\n%s", err, moreInformationMessage)
@@ -326,7 +489,7 @@ func checkConfiguration() {
 		os.Exit(1)
 	}
 
-	err = datasource.CheckAccessToken(c.GithubAccessToken, c.GithubUsername)
+	err = datasource.CheckAccessToken(c)
 	if err != nil {
 		fmt.Printf("%s\n%s", err, moreInformationMessage)
 		tracking.SendMetric("confcheck.checkaccesstoken.error")
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	tea "github.com/charmbracelet/bubbletea"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/ui"
 )
 
 var _ = Describe("cmd", func() {
@@ -11,4 +15,33 @@ var _ = Describe("cmd", func() {
 			Expect("foo").ToNot(Equal("bar"))
 		})
 	})
+
+	Describe("model", func() {
+		var m *model
+
+		BeforeEach(func() {
+			view, err := ui.NewFilteredPRView(datasource.DefaultTabs[0])
+			Expect(err).NotTo(HaveOccurred())
+			m = &model{views: []ui.PRViewData{view}}
+		})
+
+		key := func(k string) tea.KeyMsg {
+			return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+
+		It("should ignore PR shortcuts on an empty tab", func() {
+			for _, k := range []string{"a", "x", "c", "w", "d"} {
+				_, cmd := m.Update(key(k))
+				Expect(cmd).To(BeNil(), k)
+			}
+			Expect(m.reviewInput).To(BeNil())
+			Expect(m.detailView).To(BeNil())
+		})
+
+		It("should open a checked out worktree once the UI has quit", func() {
+			_, cmd := m.Update(worktreeMsg("/tmp/pr-12"))
+			Expect(m.launchDir).To(Equal("/tmp/pr-12"))
+			Expect(cmd).NotTo(BeNil())
+		})
+	})
 })
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/inburst/prty/api/handlers"
+	"github.com/inburst/prty/api/server"
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/stats"
+)
+
+// Serves the api backed by a datasource that refreshes itself every interval
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	interval := flags.Duration("interval", 10*time.Minute, "time between refreshes, 0 to only refresh on request")
+	flags.Parse(args)
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	// validate the tabs up front rather than on the first request
+	tabs := datasource.TabConfigs(c)
+	for _, tab := range tabs {
+		if _, _, err = datasource.ParseTab(tab); err != nil {
+			return fmt.Errorf("tab [%s]: %s", tab.Name, err)
+		}
+	}
+	events := handlers.NewBroadcaster()
+	h, err := newHeadlessDatasource(c, events)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			h.RefreshData()
+			if *interval <= 0 {
+				return
+			}
+			time.Sleep(*interval)
+		}
+	}()
+
+	fmt.Printf("Serving %d cached PRs on %s\n", h.NumPulls(), *addr)
+	return server.Listen(*addr, &handlers.API{
+		Source:    h,
+		Tabs:      tabs,
+		Events:    events,
+		LoadStats: stats.LoadStats,
+	})
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/stats"
+	"github.com/inburst/prty/tracking"
+)
+
+// Fits the importance model on the recorded history and prints the weights
+func trainModel() {
+	tracking.SendMetric("train")
+
+	model, err := stats.Train()
+	if err != nil {
+		fmt.Printf("Error training model: %s\n", err)
+		os.Exit(1)
+	}
+	if err = model.SaveToFile(); err != nil {
+		fmt.Printf("Error saving model: %s\n", err)
+		os.Exit(1)
+	}
+
+	modelPath, _ := config.GetModelFilePath()
+	fmt.Printf("Trained on %d events, model saved to %s\n\n", model.Samples, modelPath)
+	for i, name := range model.Features {
+		fmt.Printf("%-24s %8.3f\n", name, model.Weights[i])
+	}
+	fmt.Printf("%-24s %8.3f\n", "Bias", model.Bias)
+	fmt.Printf("\nSet UseLearnedModel: true in %s to rank PRs with it\n", config.ConfFileName)
+}
@@ -7,8 +7,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/imdario/mergo"
+	"github.com/inburst/prty/filter"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,15 +22,25 @@ const StatsFileName = "stats.json"
 const PRCacheFileName = "prs.json"
 const LogFileName = "prty.log"
 const TrainingDataFileName = "training.json"
+const ModelFileName = "model.json"
+const HTTPCacheDirName = "http-cache"
+const WorktreesDirName = "worktrees"
+const WorktreesFileName = "worktrees.json"
 const DefaultGithubToken = "token with repo read permission"
 const DefaultGithubUserName = "your github username"
 
+const FetchBackendREST = "rest"
+const FetchBackendGraphQL = "graphql"
+
 type Config struct {
 	ConfigVersion int `yaml:"ConfigVersion"`
 
 	PublicRepos []string `yaml:"PublicRepos"`
 
 	GithubAccessToken string   `yaml:"GithubAccessToken"`
+	GithubBaseURL     string   `yaml:"GithubBaseURL"`
+	GithubUploadURL   string   `yaml:"GithubUploadURL"`
+	GithubCACertPath  string   `yaml:"GithubCACertPath"`
 	OrgWhitelist      []string `yaml:"OrgWhitelist"`
 	OrgBlacklist      []string `yaml:"OrgBlacklist"`
 	RepoWhitelist     []string `yaml:"RepoWhitelist"`
@@ -36,7 +49,67 @@ type Config struct {
 	BotUsernames      []string `yaml:"BotUsernames"`
 	TeamUsernames     []string `yaml:"TeamUsernames"`
 	AbandonedAgeDays  int      `yaml:"AbandonedAgeDays"`
-	RefreshOnStart    bool     `yaml:"RefreshOnStart"`
+	// "org/repo" to days, overrides AbandonedAgeDays for that repo
+	RepoAbandonedAgeDays map[string]int `yaml:"RepoAbandonedAgeDays"`
+	WorkingHours         *WorkingHours  `yaml:"WorkingHours"`
+	RefreshOnStart       bool           `yaml:"RefreshOnStart"`
+	FetchBackend         string         `yaml:"FetchBackend"`
+	RefreshWorkers       int            `yaml:"RefreshWorkers"`
+	UseLearnedModel      bool           `yaml:"UseLearnedModel"`
+	// directories holding local clones as <root>/<org>/<repo>. Missing
+	// clones are made under the first root.
+	CheckoutRoots []string `yaml:"CheckoutRoots"`
+
+	GitlabAccessToken string `yaml:"GitlabAccessToken"`
+	GitlabBaseURL     string `yaml:"GitlabBaseURL"`
+	GitlabUsername    string `yaml:"GitlabUsername"`
+
+	Tabs            []TabConfig `yaml:"Tabs"`
+	HideDefaultTabs bool        `yaml:"HideDefaultTabs"`
+
+	// loaded from scoring.yaml
+	Scoring *Scoring `yaml:"-"`
+}
+
+// When set, waiting times are measured in working hours only
+type WorkingHours struct {
+	// IANA name like America/New_York, defaults to the local timezone
+	Timezone  string `yaml:"Timezone"`
+	StartHour int    `yaml:"StartHour"`
+	EndHour   int    `yaml:"EndHour"`
+	// Mon, Tue, ... defaults to Mon-Fri
+	Workdays []string `yaml:"Workdays"`
+	// YYYY-MM-DD
+	Holidays []string `yaml:"Holidays"`
+}
+
+const HolidayDateFormat = "2006-01-02"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parses a weekday name, only the first three letters are significant
+func ParseWeekday(name string) (time.Weekday, bool) {
+	if len(name) < 3 {
+		return 0, false
+	}
+	day, ok := weekdayNames[strings.ToLower(name[:3])]
+	return day, ok
+}
+
+// A tab shows every PR matching Filter ordered by Sort. See the filter
+// package for the expression syntax.
+type TabConfig struct {
+	Name   string `yaml:"Name"`
+	Filter string `yaml:"Filter"`
+	Sort   string `yaml:"Sort,omitempty"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -51,6 +124,11 @@ func LoadConfig() (*Config, error) {
 	if overrides, err := loadRelativeConfigOverride(); err == nil {
 		mergo.Merge(c, overrides, mergo.WithOverride)
 	}
+
+	c.Scoring, err = LoadScoring()
+	if err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -122,6 +200,79 @@ func validate(c *Config) error {
 		errFormat := "AbandonedAgeDays must be a value greater than or equal to 0, currently [%d]\n Config file can be found at %s\n"
 		return errors.New(fmt.Sprintf(errFormat, c.AbandonedAgeDays, filePath))
 	}
+
+	for repo, days := range c.RepoAbandonedAgeDays {
+		if days < 0 {
+			errFormat := "RepoAbandonedAgeDays for [%s] must be a value greater than or equal to 0, currently [%d]\n Config file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, repo, days, filePath))
+		}
+	}
+
+	if c.WorkingHours != nil {
+		if err := validateWorkingHours(c.WorkingHours); err != nil {
+			errFormat := "WorkingHours %s\n Config file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, err, filePath))
+		}
+	}
+
+	if c.RefreshWorkers < 0 {
+		errFormat := "RefreshWorkers must be a value greater than or equal to 0, currently [%d]\n Config file can be found at %s\n"
+		return errors.New(fmt.Sprintf(errFormat, c.RefreshWorkers, filePath))
+	}
+
+	if len(c.GitlabAccessToken) > 0 && len(c.GitlabUsername) == 0 {
+		errFormat := "GitlabUsername must be set when a GitlabAccessToken is provided\n Config file can be found at %s\n"
+		return errors.New(fmt.Sprintf(errFormat, filePath))
+	}
+
+	if len(c.FetchBackend) == 0 {
+		c.FetchBackend = FetchBackendREST
+	}
+	if c.FetchBackend != FetchBackendREST && c.FetchBackend != FetchBackendGraphQL {
+		errFormat := "FetchBackend must be one of [%s, %s], currently [%s]\n Config file can be found at %s\n"
+		return errors.New(fmt.Sprintf(errFormat, FetchBackendREST, FetchBackendGraphQL, c.FetchBackend, filePath))
+	}
+
+	for _, tab := range c.Tabs {
+		if len(tab.Name) == 0 {
+			errFormat := "Every entry in Tabs must have a Name\n Config file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, filePath))
+		}
+		if _, err := filter.Parse(tab.Filter); err != nil {
+			errFormat := "Tab [%s] has an invalid Filter: %s\n Config file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, tab.Name, err, filePath))
+		}
+		if len(tab.Sort) > 0 {
+			if _, err := filter.ParseSort(tab.Sort); err != nil {
+				errFormat := "Tab [%s] has an invalid Sort: %s\n Config file can be found at %s\n"
+				return errors.New(fmt.Sprintf(errFormat, tab.Name, err, filePath))
+			}
+		}
+	}
+	if c.HideDefaultTabs && len(c.Tabs) == 0 {
+		errFormat := "HideDefaultTabs requires at least one entry in Tabs\n Config file can be found at %s\n"
+		return errors.New(fmt.Sprintf(errFormat, filePath))
+	}
+	return nil
+}
+
+func validateWorkingHours(wh *WorkingHours) error {
+	if _, err := time.LoadLocation(wh.Timezone); err != nil {
+		return fmt.Errorf("Timezone [%s] is not a known timezone", wh.Timezone)
+	}
+	if wh.StartHour < 0 || wh.EndHour > 24 || wh.StartHour >= wh.EndHour {
+		return fmt.Errorf("StartHour [%d] and EndHour [%d] must be between 0 and 24 with StartHour first", wh.StartHour, wh.EndHour)
+	}
+	for _, day := range wh.Workdays {
+		if _, ok := ParseWeekday(day); !ok {
+			return fmt.Errorf("Workdays entry [%s] is not a day of the week", day)
+		}
+	}
+	for _, holiday := range wh.Holidays {
+		if _, err := time.Parse(HolidayDateFormat, holiday); err != nil {
+			return fmt.Errorf("Holidays entry [%s] must be formatted as YYYY-MM-DD", holiday)
+		}
+	}
 	return nil
 }
 
@@ -144,6 +295,8 @@ func checkAndCreateConfigFile() error {
 			GithubUsername:    DefaultGithubUserName,
 			AbandonedAgeDays:  21,
 			RefreshOnStart:    true,
+			FetchBackend:      FetchBackendREST,
+			RefreshWorkers:    4,
 		}
 		err = blankConfig.SaveToFile()
 		if err != nil {
@@ -218,3 +371,19 @@ func GetLogFilePath() (string, error) {
 func GetTrainingFilePath() (string, error) {
 	return buildScopedPathFor(TrainingDataFileName)
 }
+
+func GetModelFilePath() (string, error) {
+	return buildScopedPathFor(ModelFileName)
+}
+
+func GetHTTPCachePath() (string, error) {
+	return buildScopedPathFor(HTTPCacheDirName)
+}
+
+func GetWorktreesPath() (string, error) {
+	return buildScopedPathFor(WorktreesDirName)
+}
+
+func GetWorktreesFilePath() (string, error) {
+	return buildScopedPathFor(WorktreesFileName)
+}
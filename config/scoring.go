@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ScoringFileName = "scoring.yaml"
+
+// Curves map a feature's raw value x to a score before weighting
+//
+//	constant:  1
+//	linear:    x / Scale + Offset
+//	quadratic: x^2 / Scale + Offset
+//	inverse:   Scale / (x + Offset)
+const CurveConstant = "constant"
+const CurveLinear = "linear"
+const CurveQuadratic = "quadratic"
+const CurveInverse = "inverse"
+
+// Feature names double as the labels shown in a PR's importance breakdown
+const FeatureNotMine = "Not mine"
+const FeatureTeammate = "Teammate"
+const FeatureBot = "Not bot"
+const FeatureRequestedMe = "Requested me"
+const FeatureRequestedTeam = "Requested team"
+const FeatureOwner = "Owner"
+const FeatureCodeDelta = "Code delta"
+const FeatureReviewers = "Reviewers"
+const FeatureChecksFailing = "Checks failing"
+const FeatureFixChecks = "Fix checks"
+const FeatureConflicts = "Conflicts"
+const FeatureFixConflicts = "Fix conflicts"
+const FeatureChecksPending = "Checks pending"
+const FeatureChangeReplies = "Change replies"
+const FeatureRecentChanges = "Recent changes"
+const FeatureRecentComment = "Recent comment"
+
+type ScoringRule struct {
+	Weight float64  `yaml:"Weight"`
+	Curve  string   `yaml:"Curve"`
+	Scale  float64  `yaml:"Scale,omitempty"`
+	Offset float64  `yaml:"Offset,omitempty"`
+	Min    *float64 `yaml:"Min,omitempty"`
+	Max    *float64 `yaml:"Max,omitempty"`
+}
+
+type Scoring struct {
+	Features map[string]*ScoringRule `yaml:"Features"`
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+// The weights prty has always used. Features left out of scoring.yaml fall
+// back to these.
+func DefaultScoring() *Scoring {
+	return &Scoring{
+		Features: map[string]*ScoringRule{
+			FeatureNotMine:       {Weight: 100, Curve: CurveConstant},
+			FeatureTeammate:      {Weight: 100, Curve: CurveConstant},
+			FeatureBot:           {Weight: -50, Curve: CurveConstant},
+			FeatureRequestedMe:   {Weight: 150, Curve: CurveConstant},
+			FeatureRequestedTeam: {Weight: 75, Curve: CurveConstant},
+			FeatureOwner:         {Weight: 100, Curve: CurveConstant},
+			// lines changed
+			FeatureCodeDelta: {Weight: 1, Curve: CurveInverse, Scale: 1000, Offset: 100, Min: bound(0), Max: bound(100)},
+			// number of requested reviewers
+			FeatureReviewers:     {Weight: 0.5, Curve: CurveInverse, Scale: 1000, Offset: 5, Min: bound(0), Max: bound(100)},
+			FeatureChecksFailing: {Weight: -75, Curve: CurveConstant},
+			FeatureFixChecks:     {Weight: 100, Curve: CurveConstant},
+			FeatureConflicts:     {Weight: -50, Curve: CurveConstant},
+			FeatureFixConflicts:  {Weight: 100, Curve: CurveConstant},
+			FeatureChecksPending: {Weight: -25, Curve: CurveConstant},
+			// minutes since the last commit
+			FeatureChangeReplies: {Weight: 1, Curve: CurveQuadratic, Scale: 80000, Min: bound(0), Max: bound(250)},
+			FeatureRecentChanges: {Weight: 1, Curve: CurveQuadratic, Scale: 80000, Min: bound(0), Max: bound(50)},
+			// minutes since the last comment
+			FeatureRecentComment: {Weight: 1, Curve: CurveQuadratic, Scale: 20000, Min: bound(0), Max: bound(300)},
+		},
+	}
+}
+
+// Weighted and clamped score for the raw feature value x
+func (r *ScoringRule) Score(x float64) float64 {
+	var v float64
+	switch r.Curve {
+	case CurveConstant:
+		v = 1
+	case CurveLinear:
+		v = x/r.Scale + r.Offset
+	case CurveQuadratic:
+		v = x*x/r.Scale + r.Offset
+	case CurveInverse:
+		v = r.Scale / (x + r.Offset)
+	}
+	v *= r.Weight
+	if r.Min != nil && v < *r.Min {
+		v = *r.Min
+	}
+	if r.Max != nil && v > *r.Max {
+		v = *r.Max
+	}
+	return v
+}
+
+// Unknown features score 0
+func (s *Scoring) Score(feature string, x float64) float64 {
+	rule, ok := s.Features[feature]
+	if !ok {
+		return 0
+	}
+	return rule.Score(x)
+}
+
+func LoadScoring() (*Scoring, error) {
+	err := checkAndCreateScoringFile()
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := GetScoringFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := &Scoring{}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	s := DefaultScoring()
+	for name, rule := range overrides.Features {
+		s.Features[name] = rule
+	}
+	if err = validateScoring(s, overrides, filePath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func validateScoring(s *Scoring, overrides *Scoring, filePath string) error {
+	defaults := DefaultScoring()
+	names := []string{}
+	for name := range overrides.Features {
+		names = append(names, name)
+	}
+	// report errors in a stable order
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := defaults.Features[name]; !ok {
+			errFormat := "Unknown scoring feature [%s]\n Scoring file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, name, filePath))
+		}
+
+		rule := s.Features[name]
+		if rule == nil {
+			errFormat := "Scoring feature [%s] must define a Weight and Curve\n Scoring file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, name, filePath))
+		}
+		switch rule.Curve {
+		case CurveConstant:
+		case CurveLinear, CurveQuadratic, CurveInverse:
+			if rule.Scale == 0 {
+				errFormat := "Scoring feature [%s] must set a non zero Scale for the %s curve\n Scoring file can be found at %s\n"
+				return errors.New(fmt.Sprintf(errFormat, name, rule.Curve, filePath))
+			}
+			// counts and ages start at zero, which would divide by zero
+			if rule.Curve == CurveInverse && rule.Offset <= 0 && rule.Max == nil {
+				errFormat := "Scoring feature [%s] must set a positive Offset or a Max for the %s curve\n Scoring file can be found at %s\n"
+				return errors.New(fmt.Sprintf(errFormat, name, rule.Curve, filePath))
+			}
+		default:
+			errFormat := "Scoring feature [%s] Curve must be one of [%s, %s, %s, %s], currently [%s]\n Scoring file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, name, CurveConstant, CurveLinear, CurveQuadratic, CurveInverse, rule.Curve, filePath))
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			errFormat := "Scoring feature [%s] Min [%g] must not be greater than Max [%g]\n Scoring file can be found at %s\n"
+			return errors.New(fmt.Sprintf(errFormat, name, *rule.Min, *rule.Max, filePath))
+		}
+	}
+	return nil
+}
+
+// Writes out the defaults commented out so there is something to edit.
+// Only features that are uncommented override the defaults, the rest keep
+// following them when they change in a later version.
+func checkAndCreateScoringFile() error {
+	err := PrepApplicationCacheFolder()
+	if err != nil {
+		return err
+	}
+
+	scoringPath, err := GetScoringFilePath()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(scoringPath); os.IsNotExist(err) {
+		return ioutil.WriteFile(scoringPath, scoringStub(), 0644)
+	}
+	return nil
+}
+
+const scoringStubHeader = `# Uncomment Features and any feature below to override its default.
+# Features left commented out use the built in defaults.
+`
+
+func scoringStub() []byte {
+	var b bytes.Buffer
+	yamlEncoder := yaml.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	yamlEncoder.Encode(DefaultScoring())
+
+	stub := bytes.NewBufferString(scoringStubHeader)
+	for _, line := range strings.SplitAfter(b.String(), "\n") {
+		if len(line) > 0 {
+			stub.WriteString("# " + line)
+		}
+	}
+	return stub.Bytes()
+}
+
+func GetScoringFilePath() (string, error) {
+	return buildScopedPathFor(ScoringFileName)
+}
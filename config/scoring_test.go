@@ -0,0 +1,109 @@
+package config
+
+import (
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scoring", func() {
+	Describe("DefaultScoring", func() {
+		s := DefaultScoring()
+
+		It("should score flags with their weight", func() {
+			Expect(s.Score(FeatureNotMine, 0)).To(Equal(100.0))
+			Expect(s.Score(FeatureBot, 0)).To(Equal(-50.0))
+		})
+
+		It("should match the original code delta curve", func() {
+			for _, delta := range []float64{0, 10, 250, 5000} {
+				expected := math.Min(math.Max((1/(delta+100))*1000, 0), 100)
+				Expect(s.Score(FeatureCodeDelta, delta)).To(BeNumerically("~", expected, 1e-9))
+			}
+		})
+
+		It("should match the original time since commit curves", func() {
+			for _, minutes := range []float64{0, 60, 2880, 100000} {
+				expected := math.Min(math.Pow(minutes, 2)/80000, 250)
+				Expect(s.Score(FeatureChangeReplies, minutes)).To(BeNumerically("~", expected, 1e-9))
+			}
+		})
+
+		It("should score unknown features as 0", func() {
+			Expect(s.Score("Unknown", 10)).To(Equal(0.0))
+		})
+	})
+
+	Describe("validateScoring", func() {
+		validate := func(rules map[string]*ScoringRule) error {
+			overrides := &Scoring{Features: rules}
+			s := DefaultScoring()
+			for name, rule := range rules {
+				s.Features[name] = rule
+			}
+			return validateScoring(s, overrides, "scoring.yaml")
+		}
+
+		It("should accept overrides of known features", func() {
+			Expect(validate(map[string]*ScoringRule{
+				FeatureTeammate: {Weight: 200, Curve: CurveConstant},
+			})).To(Succeed())
+		})
+
+		It("should reject unknown features", func() {
+			Expect(validate(map[string]*ScoringRule{
+				"Made up": {Weight: 1, Curve: CurveConstant},
+			})).NotTo(Succeed())
+		})
+
+		It("should reject unknown curves and missing scales", func() {
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: "cubic", Scale: 1},
+			})).NotTo(Succeed())
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: CurveInverse},
+			})).NotTo(Succeed())
+		})
+
+		It("should reject inverse curves that can divide by zero", func() {
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: CurveInverse, Scale: 1000},
+			})).NotTo(Succeed())
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: CurveInverse, Scale: 1000, Offset: -5},
+			})).NotTo(Succeed())
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: CurveInverse, Scale: 1000, Max: bound(100)},
+			})).To(Succeed())
+		})
+
+		It("should reject a min above the max", func() {
+			Expect(validate(map[string]*ScoringRule{
+				FeatureCodeDelta: {Weight: 1, Curve: CurveInverse, Scale: 1, Min: bound(10), Max: bound(5)},
+			})).NotTo(Succeed())
+		})
+	})
+
+	Describe("scoringStub", func() {
+		It("should override nothing as written", func() {
+			overrides := &Scoring{}
+			Expect(yaml.Unmarshal(scoringStub(), overrides)).To(Succeed())
+			Expect(overrides.Features).To(BeEmpty())
+		})
+
+		It("should hold the defaults once uncommented", func() {
+			lines := []string{}
+			body := strings.TrimPrefix(string(scoringStub()), scoringStubHeader)
+			for _, line := range strings.Split(body, "\n") {
+				lines = append(lines, strings.TrimPrefix(line, "# "))
+			}
+			overrides := &Scoring{}
+			Expect(yaml.Unmarshal([]byte(strings.Join(lines, "\n")), overrides)).To(Succeed())
+			Expect(overrides).To(Equal(DefaultScoring()))
+		})
+	})
+})
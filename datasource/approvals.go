@@ -0,0 +1,76 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/inburst/prty/logger"
+)
+
+// Branch protection rarely changes so it is only refetched this often
+const approvalRulesTTL = time.Hour
+
+// Used until a branch's rules are known and for unprotected branches, which
+// still expect someone to review before merging
+const defaultRequiredApprovals = 1
+
+type branchApprovalRules struct {
+	required  int
+	fetchedAt time.Time
+}
+
+func (ds *Datasource) refreshApprovalRules(ctx context.Context, ap approvalRulesProvider, repoKey string, orgName string, repoName string, branch string) {
+	key := repoKey + "/" + branch
+	ds.mutex.RLock()
+	existing, ok := ds.approvalRules[key]
+	ds.mutex.RUnlock()
+	if ok && time.Since(existing.fetchedAt) < approvalRulesTTL {
+		return
+	}
+
+	ds.writeStatus(fmt.Sprintf("%s/%s fetching approval rules for %s...", orgName, repoName, branch))
+	required, err := ap.GetRequiredApprovals(ctx, orgName, repoName, branch)
+	if err != nil {
+		// reading protection needs admin rights on github so this is common
+		logger.Shared().Printf("error getting approval rules for [%s/%s:%s] %s\n", orgName, repoName, branch, err)
+		required = defaultRequiredApprovals
+	}
+	ds.mutex.Lock()
+	ds.approvalRules[key] = &branchApprovalRules{required: required, fetchedAt: time.Now()}
+	ds.mutex.Unlock()
+}
+
+func (ds *Datasource) requiredApprovalsFor(pr *PullRequest) int {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	rules, ok := ds.approvalRules[fmt.Sprintf("%s/%s/%s/%s", pr.Provider, pr.OrgName, pr.RepoName, pr.BaseBranch)]
+	if !ok || rules.required < defaultRequiredApprovals {
+		return defaultRequiredApprovals
+	}
+	return rules.required
+}
+
+// Counts each reviewer's latest verdict. Approvals given on an older commit
+// are stale and do not count, and any standing change request blocks the PR
+// however many approvals it has.
+func (pr *PullRequest) calculateApprovals(required int) {
+	pr.ApprovalCount = 0
+	pr.StaleApprovalCount = 0
+	pr.RequiredApprovals = required
+
+	changesRequested := false
+	for _, r := range pr.latestReviews() {
+		switch r.State {
+		case "APPROVED":
+			if len(r.CommitSHA) > 0 && len(pr.HeadSHA) > 0 && r.CommitSHA != pr.HeadSHA {
+				pr.StaleApprovalCount++
+			} else {
+				pr.ApprovalCount++
+			}
+		case "CHANGES_REQUESTED":
+			changesRequested = true
+		}
+	}
+	pr.IsApproved = !changesRequested && pr.ApprovalCount >= required
+}
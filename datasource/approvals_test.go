@@ -0,0 +1,92 @@
+package datasource
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("calculateApprovals", func() {
+	start := time.Date(2021, time.May, 24, 9, 0, 0, 0, time.UTC)
+	review := func(author string, state string, sha string, hour int) *Review {
+		return &Review{
+			Author:      author,
+			State:       state,
+			CommitSHA:   sha,
+			SubmittedAt: start.Add(time.Duration(hour) * time.Hour),
+		}
+	}
+
+	var pr *PullRequest
+	BeforeEach(func() {
+		pr = &PullRequest{Author: "author", HeadSHA: "head"}
+	})
+
+	It("should use each reviewer's latest verdict", func() {
+		pr.Reviews = []*Review{
+			review("alice", "APPROVED", "head", 1),
+			review("alice", "CHANGES_REQUESTED", "head", 2),
+		}
+		pr.calculateApprovals(1)
+		Expect(pr.ApprovalCount).To(Equal(0))
+		Expect(pr.IsApproved).To(BeFalse())
+
+		pr.Reviews = append(pr.Reviews, review("alice", "APPROVED", "head", 3))
+		pr.calculateApprovals(1)
+		Expect(pr.ApprovalCount).To(Equal(1))
+		Expect(pr.IsApproved).To(BeTrue())
+	})
+
+	It("should keep a verdict through later plain comments", func() {
+		pr.Reviews = []*Review{
+			review("alice", "APPROVED", "head", 1),
+			review("alice", "COMMENTED", "head", 2),
+		}
+		pr.calculateApprovals(1)
+		Expect(pr.IsApproved).To(BeTrue())
+	})
+
+	It("should not count approvals of older commits", func() {
+		pr.Reviews = []*Review{review("alice", "APPROVED", "old", 1)}
+		pr.calculateApprovals(1)
+		Expect(pr.ApprovalCount).To(Equal(0))
+		Expect(pr.StaleApprovalCount).To(Equal(1))
+		Expect(pr.IsApproved).To(BeFalse())
+	})
+
+	It("should need the required number of approvals", func() {
+		pr.Reviews = []*Review{review("alice", "APPROVED", "head", 1)}
+		pr.calculateApprovals(2)
+		Expect(pr.ApprovalCount).To(Equal(1))
+		Expect(pr.RequiredApprovals).To(Equal(2))
+		Expect(pr.IsApproved).To(BeFalse())
+
+		pr.Reviews = append(pr.Reviews, review("bob", "APPROVED", "head", 2))
+		pr.calculateApprovals(2)
+		Expect(pr.IsApproved).To(BeTrue())
+	})
+
+	It("should block on any standing change request", func() {
+		pr.Reviews = []*Review{
+			review("alice", "APPROVED", "head", 1),
+			review("bob", "CHANGES_REQUESTED", "head", 2),
+		}
+		pr.calculateApprovals(1)
+		Expect(pr.IsApproved).To(BeFalse())
+
+		// dismissing a review changes its state in place
+		pr.Reviews[1].State = "DISMISSED"
+		pr.calculateApprovals(1)
+		Expect(pr.IsApproved).To(BeTrue())
+	})
+
+	It("should count approvals without a time or commit", func() {
+		// gitlab approvals when the approval note is missing
+		pr.Reviews = []*Review{{Author: "alice", State: "APPROVED"}}
+		pr.calculateApprovals(1)
+		Expect(pr.ApprovalCount).To(Equal(1))
+		Expect(pr.IsApproved).To(BeTrue())
+		Expect(pr.buildActivity()).To(BeEmpty())
+	})
+})
@@ -3,25 +3,38 @@ package datasource
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
-	"github.com/google/go-github/v53/github"
-	"golang.org/x/oauth2"
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/logger"
 )
 
-func CheckAccessToken(tok string, userName string) error {
+func CheckAccessToken(c *config.Config) error {
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: tok},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	sharedGithubClient = github.NewClient(tc)
-	_, resp, err := sharedClient().Users.Get(ctx, userName)
+	client, err := newGithubClient(c)
 	if err != nil {
 		return err
 	}
-	scopesStr := resp.Header.Get("X-OAuth-Scopes")
-	scopes := strings.Split(scopesStr, ",")
+	sharedGithubClient = client
+
+	// the authenticated user endpoint behaves the same on github.com and
+	// enterprise servers, where /users/{name} may be hidden by private mode
+	user, resp, err := sharedClient().Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("Unable to reach %s: %s", sharedClient().BaseURL, err)
+	}
+	// a shared or bot token is fine, PRs are still scored for GithubUsername
+	if !strings.EqualFold(user.GetLogin(), c.GithubUsername) {
+		logger.Shared().Printf("warning: the access token belongs to [%s] but GithubUsername is [%s]\n", user.GetLogin(), c.GithubUsername)
+	}
+
+	// enterprise servers older than 2.x and fine grained tokens do not report
+	// scopes, the api calls themselves will fail if access is missing
+	if _, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; !ok {
+		return nil
+	}
+	scopes := strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",")
 	expectedScopes := []string{
 		"repo",
 		"read:org",
@@ -31,7 +44,8 @@ func CheckAccessToken(tok string, userName string) error {
 		found := false
 		for _, s := range scopes {
 			trimmed := strings.Trim(s, " ")
-			if trimmed == es {
+			// admin:org and write:org both include read:org
+			if trimmed == es || (es == "read:org" && strings.HasSuffix(trimmed, ":org")) {
 				found = true
 				break
 			}
@@ -43,5 +57,5 @@ func CheckAccessToken(tok string, userName string) error {
 	if len(missing) == 0 {
 		return nil
 	}
-	return fmt.Errorf("Required scopes are [repo, org:read]. Did not find [%s].\nPlease re-issue a new token with the required scopes.", strings.Join(missing, ","))
+	return fmt.Errorf("Required scopes are [repo, read:org]. Did not find [%s].\nPlease re-issue a new token with the required scopes.", strings.Join(missing, ","))
 }
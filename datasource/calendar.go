@@ -0,0 +1,90 @@
+package datasource
+
+import (
+	"time"
+
+	"github.com/inburst/prty/config"
+)
+
+const DefaultAbandonedAgeDays = 21
+
+// calendar measures elapsed time in working hours only. A nil calendar
+// measures wall time.
+type calendar struct {
+	loc       *time.Location
+	startHour int
+	endHour   int
+	workdays  map[time.Weekday]bool
+	holidays  map[string]bool
+}
+
+// Returns nil when no working hours are configured. The config has already
+// been validated so parse errors are not expected here.
+func newCalendar(wh *config.WorkingHours) *calendar {
+	if wh == nil {
+		return nil
+	}
+	c := &calendar{
+		loc:       time.Local,
+		startHour: wh.StartHour,
+		endHour:   wh.EndHour,
+		workdays:  map[time.Weekday]bool{},
+		holidays:  map[string]bool{},
+	}
+	if len(wh.Timezone) > 0 {
+		if loc, err := time.LoadLocation(wh.Timezone); err == nil {
+			c.loc = loc
+		}
+	}
+	for _, name := range wh.Workdays {
+		if day, ok := config.ParseWeekday(name); ok {
+			c.workdays[day] = true
+		}
+	}
+	if len(c.workdays) == 0 {
+		for day := time.Monday; day <= time.Friday; day++ {
+			c.workdays[day] = true
+		}
+	}
+	for _, holiday := range wh.Holidays {
+		c.holidays[holiday] = true
+	}
+	return c
+}
+
+func (c *calendar) since(t time.Time) time.Duration {
+	return c.between(t, time.Now())
+}
+
+// Sums the overlap of [from, to] with every working day's hours
+func (c *calendar) between(from time.Time, to time.Time) time.Duration {
+	if c == nil {
+		return to.Sub(from)
+	}
+	if !to.After(from) {
+		return 0
+	}
+
+	from = from.In(c.loc)
+	to = to.In(c.loc)
+	total := time.Duration(0)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.loc)
+	for !day.After(to) {
+		if c.workdays[day.Weekday()] && !c.holidays[day.Format(config.HolidayDateFormat)] {
+			start := time.Date(day.Year(), day.Month(), day.Day(), c.startHour, 0, 0, 0, c.loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), c.endHour, 0, 0, 0, c.loc)
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+		// AddDate keeps midnight across daylight saving changes
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
@@ -0,0 +1,48 @@
+package datasource
+
+import (
+	"time"
+
+	"github.com/inburst/prty/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("calendar", func() {
+	Describe("between", func() {
+		c := newCalendar(&config.WorkingHours{
+			Timezone:  "UTC",
+			StartHour: 9,
+			EndHour:   17,
+			Holidays:  []string{"2021-05-31"},
+		})
+		at := func(day int, hour int) time.Time {
+			// May 2021, the 24th is a Monday
+			return time.Date(2021, time.May, day, hour, 0, 0, 0, time.UTC)
+		}
+
+		It("should measure wall time without working hours", func() {
+			var wall *calendar
+			Expect(wall.between(at(24, 9), at(25, 9))).To(Equal(24 * time.Hour))
+		})
+
+		It("should only count time inside working hours", func() {
+			Expect(c.between(at(24, 10), at(24, 12))).To(Equal(2 * time.Hour))
+			Expect(c.between(at(24, 16), at(25, 10))).To(Equal(2 * time.Hour))
+		})
+
+		It("should skip weekends", func() {
+			// friday 4pm to monday 10am
+			Expect(c.between(at(21, 16), at(24, 10))).To(Equal(2 * time.Hour))
+		})
+
+		It("should skip holidays", func() {
+			// friday 4pm to monday 10am with monday the 31st off
+			Expect(c.between(at(28, 16), at(31, 10))).To(Equal(time.Hour))
+		})
+
+		It("should return 0 for reversed ranges", func() {
+			Expect(c.between(at(25, 10), at(24, 10))).To(Equal(time.Duration(0)))
+		})
+	})
+})
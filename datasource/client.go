@@ -2,11 +2,16 @@ package datasource
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/go-github/v53/github"
 	"github.com/inburst/prty/config"
@@ -20,13 +25,29 @@ type Datasource struct {
 	prUpdateChan          chan<- *PullRequest
 	remainingRequestsChan chan<- github.Rate
 
-	config *config.Config
+	config    *config.Config
+	scoring   *config.Scoring
+	model     *Model
+	calendar  *calendar
+	providers []Provider
+	scheduler *scheduler
 
 	allPRs              map[string]*PullRequest
-	cachedPRs           map[string]*PullRequest
 	currentlyRefreshing bool
+	// most recent updated time seen per repo by a refresh that completed
+	// every PR in that repo. Later refreshes stop paging once they reach it.
+	repoWatermarks map[string]time.Time
+	cancelRefresh  context.CancelFunc
+	// "org/team-slug" for every team I am on, lower cased
+	myTeams    map[string]bool
+	codeOwners map[string]*repoCodeOwners
+	// required approvals per "provider/org/repo/branch"
+	approvalRules map[string]*branchApprovalRules
 
 	mutex sync.RWMutex
+	// held for the full duration of a refresh so a new refresh waits for
+	// the cancelled one to drain before touching allPRs
+	refreshMutex sync.Mutex
 }
 
 var sharedGithubClient *github.Client
@@ -35,24 +56,132 @@ func sharedClient() *github.Client {
 	return sharedGithubClient
 }
 
-func InitSharedClient(tok string) error {
-	ctx := context.Background()
+func InitSharedClient(c *config.Config) error {
+	client, err := newGithubClient(c)
+	if err != nil {
+		return err
+	}
+	sharedGithubClient = client
+	return nil
+}
+
+// Builds a client for github.com or, when a base url is configured, a
+// github enterprise server instance
+func newGithubClient(c *config.Config) (*github.Client, error) {
+	transport, err := newCachingTransport(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// oauth2 wraps whatever client is stored on the context
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: tok},
+		&oauth2.Token{AccessToken: c.GithubAccessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 
-	sharedGithubClient = github.NewClient(tc)
+	if len(c.GithubBaseURL) == 0 {
+		return github.NewClient(tc), nil
+	}
 
-	return nil
+	uploadURL := c.GithubUploadURL
+	if len(uploadURL) == 0 {
+		uploadURL = c.GithubBaseURL
+	}
+	return github.NewEnterpriseClient(c.GithubBaseURL, uploadURL, tc)
+}
+
+// Default transport plus any custom certificate authorities. Enterprise
+// installs are often signed by an internal CA the system does not trust.
+func newBaseTransport(c *config.Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(c.GithubCACertPath) == 0 {
+		return transport, nil
+	}
+
+	pem, err := ioutil.ReadFile(c.GithubCACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GithubCACertPath %s: %s", c.GithubCACertPath, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in GithubCACertPath %s", c.GithubCACertPath)
+	}
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return transport, nil
+}
+
+// Conditional requests are served from the ~/.prty http cache when possible
+func newCachingTransport(c *config.Config) (http.RoundTripper, error) {
+	base, err := newBaseTransport(c)
+	if err != nil {
+		return nil, err
+	}
+	cachePath, err := config.GetHTTPCachePath()
+	if err != nil {
+		return nil, err
+	}
+	return newETagTransport(base, cachePath, defaultHTTPCacheEntries), nil
 }
 
 func New(c *config.Config) *Datasource {
 	ds := &Datasource{}
 	ds.config = c
+	ds.scoring = c.Scoring
+	ds.calendar = newCalendar(c.WorkingHours)
+	if ds.scoring == nil {
+		ds.scoring = config.DefaultScoring()
+	}
+	if c.UseLearnedModel {
+		model, err := LoadModel()
+		if err != nil {
+			// fall back to the configured weights
+			logger.Shared().Printf("unable to load learned model %s\n", err)
+		}
+		ds.model = model
+	}
+	ds.scheduler = newScheduler(ds)
+	ds.allPRs = map[string]*PullRequest{}
+	ds.repoWatermarks = map[string]time.Time{}
+	ds.myTeams = map[string]bool{}
+	ds.codeOwners = map[string]*repoCodeOwners{}
+	ds.approvalRules = map[string]*branchApprovalRules{}
+
+	if c.FetchBackend == config.FetchBackendGraphQL {
+		ds.providers = append(ds.providers, newGithubGraphQLProvider(ds, c.GithubUsername))
+	} else {
+		ds.providers = append(ds.providers, newGithubProvider(ds, c.GithubUsername))
+	}
+	if len(c.GitlabAccessToken) > 0 {
+		ds.providers = append(ds.providers, newGitlabProvider(ds, c.GitlabBaseURL, c.GitlabAccessToken, c.GitlabUsername))
+	}
 	return ds
 }
 
+// Days without a commit before a PR in the repo is abandoned
+func (ds *Datasource) abandonedAgeDays(orgName string, repoName string) int {
+	if days, ok := ds.config.RepoAbandonedAgeDays[orgName+"/"+repoName]; ok && days > 0 {
+		return days
+	}
+	if ds.config.AbandonedAgeDays > 0 {
+		return ds.config.AbandonedAgeDays
+	}
+	return DefaultAbandonedAgeDays
+}
+
+// The username that identifies me on the host the PR came from
+func (ds *Datasource) usernameFor(providerName string) string {
+	for _, p := range ds.providers {
+		if p.Name() == providerName {
+			return p.Username()
+		}
+	}
+	return ds.config.GithubUsername
+}
+
 func (ds *Datasource) writeErrorStatus(err error) {
 	ds.statusChan <- fmt.Sprintf("ERROR: %s", err)
 }
@@ -77,15 +206,38 @@ func (ds *Datasource) SetPRUpdateChan(prUpdateChan chan<- *PullRequest) {
 func (ds *Datasource) LoadLocalCache() {
 	// load in previous prs and emit them to the views
 	ds.allPRs = ds.loadSaveFile()
-	for _, pr := range ds.allPRs {
-		ds.prUpdateChan <- pr
+	for id, pr := range ds.allPRs {
+		// entries written before providers existed can not be refreshed
+		if len(pr.ID) == 0 {
+			delete(ds.allPRs, id)
+			continue
+		}
+		// activity and threads are not saved, rebuild them from the lists
+		pr.Activity = pr.buildActivity()
+		pr.Threads = pr.buildThreads(ds.usernameFor(pr.Provider))
+
+		// the views keep what they are sent so store the same copy
+		cur := ds.current(pr)
+		ds.allPRs[id] = cur
+		ds.prUpdateChan <- cur
 	}
 }
 
 func (ds *Datasource) IsCurrentlyRefreshingData() bool {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
 	return ds.currentlyRefreshing
 }
 
+// Cancels any in flight refresh and its outstanding http calls
+func (ds *Datasource) CancelRefresh() {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	if ds.cancelRefresh != nil {
+		ds.cancelRefresh()
+	}
+}
+
 // Supressed errors will cause the cache file to be emptied and rebuilt
 // effectivly self healing from corrupt or invalid data
 func (ds *Datasource) loadSaveFile() map[string]*PullRequest {
@@ -115,75 +267,98 @@ func (ds *Datasource) SaveToFile() {
 	_ = ioutil.WriteFile(cacheFilePath, file, 0644)
 }
 
+// Blocks until every repo and PR has been fetched or the refresh is
+// cancelled. Starting a new refresh cancels the one in flight.
 func (ds *Datasource) RefreshData() {
 	tracking.SendMetric("data.refresh")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds.CancelRefresh()
+	ds.refreshMutex.Lock()
+	defer ds.refreshMutex.Unlock()
+
+	ds.mutex.Lock()
+	ds.cancelRefresh = cancel
 	ds.currentlyRefreshing = true
-	ds.cachedPRs = ds.allPRs
-	ds.allPRs = map[string]*PullRequest{}
+	ds.mutex.Unlock()
 
-	ds.writeStatus("fetching users orgs...")
-	orgs, err := GetAllOrgs()
-	if err != nil {
-		ds.writeErrorStatus(err)
-		logger.Shared().Printf("%s\n", err)
-		tracking.SendMetric("data.getorgs.error")
+	defer func() {
+		ds.mutex.Lock()
 		ds.currentlyRefreshing = false
+		ds.mutex.Unlock()
+	}()
+
+	pool := newWorkerPool(ctx, ds.config.RefreshWorkers)
+	for _, provider := range ds.providers {
+		if err := ds.refreshProvider(ctx, pool, provider); err != nil {
+			ds.writeErrorStatus(err)
+			logger.Shared().Printf("%s\n", err)
+			break
+		}
+	}
+	pool.Wait()
+	// PRs are replaced one at a time so a cancelled refresh still leaves a
+	// consistent cache behind
+	ds.SaveToFile()
+
+	if ctx.Err() != nil {
+		logger.Shared().Println("refresh cancelled")
 		return
 	}
+	ds.writeStatus("refreshed")
+}
+
+func (ds *Datasource) refreshProvider(ctx context.Context, pool *workerPool, provider Provider) error {
+	if tp, ok := provider.(teamsProvider); ok {
+		ds.writeStatus(fmt.Sprintf("fetching users %s teams...", provider.Name()))
+		ds.refreshMyTeams(ctx, tp)
+	}
+
+	ds.writeStatus(fmt.Sprintf("fetching users %s orgs...", provider.Name()))
+	orgs, err := provider.GetAllOrgs(ctx)
+	if err != nil {
+		tracking.SendMetric("data.getorgs.error")
+		return err
+	}
 
 	ds.writeStatus("fetching users repos...")
-	for i := range orgs {
-		if orgs[i].Login != nil {
-			orgName := *orgs[i].Login
-			if listContains(ds.config.OrgBlacklist, orgName) {
-				logger.Shared().Printf("skipping due to blacklist %s\n", orgName)
+	for _, orgName := range orgs {
+		if listContains(ds.config.OrgBlacklist, orgName) {
+			logger.Shared().Printf("skipping due to blacklist %s\n", orgName)
+			continue
+		}
+		if len(ds.config.OrgWhitelist) != 0 && !listContains(ds.config.OrgWhitelist, orgName) {
+			logger.Shared().Printf("skipping due to missing in whitelist %s\n", orgName)
+			continue
+		}
+
+		ds.writeStatus(fmt.Sprintf("fetching repos for %s ...", orgName))
+		repos, err := provider.GetAllReposForOrg(ctx, orgName)
+		if err != nil {
+			tracking.SendMetric("data.getrepos.error")
+			return err
+		}
+
+		for _, repoName := range repos {
+			if len(ds.config.RepoWhitelist) != 0 && !listContains(ds.config.RepoWhitelist, repoName) {
+				logger.Shared().Printf("skipping due to whitelist %s:%s \n", orgName, repoName)
 				continue
 			}
-			if len(ds.config.OrgWhitelist) != 0 && !listContains(ds.config.OrgWhitelist, orgName) {
-				logger.Shared().Printf("skipping due to missing in whitelist %s\n", orgName)
+			if len(ds.config.RepoBlacklist) != 0 && listContains(ds.config.RepoBlacklist, repoName) {
+				logger.Shared().Printf("skipping due to blacklist %s:%s \n", orgName, repoName)
 				continue
 			}
 
-			ds.writeStatus(fmt.Sprintf("fetching repos for %s ...", orgName))
-
-			// publicRepos := map[string]bool{}
-			// for _, orgAndRepoName := range c.PublicRepos {
-			// 	repoParts := strings.Split(orgAndRepoName, "/")
-			// 	if len(repoParts) != 2 {
-			// 		logger.Shared().Printf("invalid public repo name: %s\n", orgAndRepoName)
-			// 		continue
-			// 	}
-			// 	orgName := repoParts[0]
-			// 	publicRepos[]
-			// }
-
-			repos, err := GetAllReposForOrg(orgName)
-			if err != nil {
-				ds.writeErrorStatus(err)
-				logger.Shared().Printf("%s\n", err)
-				tracking.SendMetric("data.getrepos.error")
-				ds.currentlyRefreshing = false
-				return
-			}
-
-			for _, repo := range repos {
-				repoName := *repo.Name
-				if len(ds.config.RepoWhitelist) != 0 && !listContains(ds.config.RepoWhitelist, repoName) {
-					logger.Shared().Printf("skipping due to whitelist %s:%s \n", orgName, repoName)
-					continue
-				}
-				if len(ds.config.RepoBlacklist) != 0 && listContains(ds.config.RepoBlacklist, repoName) {
-					logger.Shared().Printf("skipping due to blacklist %s:%s \n", orgName, repoName)
-					continue
-				}
-
-				go ds.refreshRepo(orgName, repoName)
-			}
-
+			ds.submitRepo(pool, provider, orgName, repoName)
 		}
 	}
 
+	// public repos are only supported for github
+	if provider.Name() != GithubProviderName {
+		return nil
+	}
 	ds.writeStatus("fetching public repos...")
 	for _, orgAndRepoName := range ds.config.PublicRepos {
 		repoParts := strings.Split(orgAndRepoName, "/")
@@ -194,16 +369,32 @@ func (ds *Datasource) RefreshData() {
 
 		orgName := repoParts[0]
 		repoName := repoParts[1]
-		go ds.refreshRepo(orgName, repoName)
+		ds.submitRepo(pool, provider, orgName, repoName)
 	}
+	return nil
+}
 
-	ds.currentlyRefreshing = false
-	ds.writeStatus("refreshed")
+func (ds *Datasource) submitRepo(pool *workerPool, provider Provider, orgName string, repoName string) {
+	pool.Submit(func(ctx context.Context) {
+		ds.refreshRepo(ctx, pool, provider, orgName, repoName)
+	})
 }
 
-func (ds *Datasource) refreshRepo(orgName string, repoName string) {
+// Lists what changed in the repo since the last completed refresh. Only new
+// or updated PRs are re-hydrated, closed ones are dropped and the rest have
+// their checks re-read and are re-scored from the cache since their time
+// based features have moved.
+func (ds *Datasource) refreshRepo(ctx context.Context, pool *workerPool, provider Provider, orgName string, repoName string) {
+	repoKey := fmt.Sprintf("%s/%s/%s", provider.Name(), orgName, repoName)
+	ds.mutex.RLock()
+	since := ds.repoWatermarks[repoKey]
+	ds.mutex.RUnlock()
+
 	ds.writeStatus(fmt.Sprintf("%s/%s fetching prs...", orgName, repoName))
-	prs, err := ds.GetAllPullsForRepoInOrg(orgName, repoName)
+	prs, err := provider.GetPullsForRepo(ctx, orgName, repoName, since)
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		ds.writeErrorStatus(err)
 		logger.Shared().Printf("%s\n", err)
@@ -211,36 +402,226 @@ func (ds *Datasource) refreshRepo(orgName string, repoName string) {
 		return
 	}
 
-	for _, ghpr := range prs {
-		go ds.buildPr(orgName, repoName, ghpr)
+	cached := ds.pullsForRepo(provider.Name(), orgName, repoName)
+	if cp, ok := provider.(codeOwnersProvider); ok && (len(prs) > 0 || len(cached) > 0) {
+		ds.refreshCodeOwners(ctx, cp, repoKey, orgName, repoName)
+	}
+	if ap, ok := provider.(approvalRulesProvider); ok {
+		branches := map[string]bool{}
+		for _, pr := range prs {
+			branches[pr.BaseBranch] = true
+		}
+		for _, pr := range cached {
+			branches[pr.BaseBranch] = true
+		}
+		for branch := range branches {
+			ds.refreshApprovalRules(ctx, ap, repoKey, orgName, repoName, branch)
+		}
+	}
+	watermark := since
+	changed := []*PullRequest{}
+	unchanged := []*PullRequest{}
+	for _, pr := range prs {
+		if pr.UpdatedAt.After(watermark) {
+			watermark = pr.UpdatedAt
+		}
+		existingPr, ok := cached[pr.ID]
+		delete(cached, pr.ID)
+
+		if pr.IsClosed {
+			if ok {
+				ds.removePull(existingPr)
+			}
+			continue
+		}
+		if ok && existingPr.UpdatedAt.Equal(pr.UpdatedAt) {
+			unchanged = append(unchanged, existingPr)
+			continue
+		}
+		if ok {
+			pr.mergeCached(existingPr)
+		}
+		changed = append(changed, pr)
 	}
-}
 
-func (ds *Datasource) buildPr(orgName string, repoName string, ghpr *github.PullRequest) {
+	// a full listing only returns open PRs so anything missing was closed,
+	// otherwise it was simply older than the watermark and has not changed
+	for _, pr := range cached {
+		if since.IsZero() {
+			ds.removePull(pr)
+		} else {
+			unchanged = append(unchanged, pr)
+		}
+	}
 
-	// Note: PRs that are sourced from the /list api will not contain all desired
-	// fields including additions and deletions
+	// unchanged PRs are mostly answered by a 304 from the http cache
+	checks, hasChecks := provider.(checksProvider)
+	for _, pr := range unchanged {
+		if !hasChecks {
+			ds.storePull(pr)
+			continue
+		}
+		pr := pr
+		pool.Submit(func(ctx context.Context) {
+			ds.refreshChecks(ctx, checks, pr)
+		})
+	}
 
-	if existingPr, ok := ds.cachedPRs[ghpr.GetNodeID()]; ok {
-		ds.UpdateExistingPr(orgName, repoName, existingPr, ghpr)
+	// the watermark only moves once every changed PR hydrated successfully
+	// so a failed or cancelled refresh is retried in full next time
+	remaining := int32(len(changed))
+	failed := int32(0)
+	advanceWatermark := func() {
 		ds.mutex.Lock()
-		ds.allPRs[existingPr.PR.GetNodeID()] = existingPr
+		ds.repoWatermarks[repoKey] = watermark
 		ds.mutex.Unlock()
-		ds.prUpdateChan <- existingPr
-	} else {
-		newPR, err := ds.BuildPullRequest(orgName, repoName, ghpr)
+	}
+	if remaining == 0 {
+		advanceWatermark()
+	}
+	for _, pr := range changed {
+		pr := pr
+		pool.Submit(func(ctx context.Context) {
+			if !ds.buildPr(ctx, provider, pr) {
+				atomic.StoreInt32(&failed, 1)
+			}
+			if atomic.AddInt32(&remaining, -1) == 0 && atomic.LoadInt32(&failed) == 0 {
+				advanceWatermark()
+			}
+		})
+	}
+}
+
+// Re-reads the checks of a cached PR and re-scores it. The last known state
+// is kept if they can not be read.
+func (ds *Datasource) refreshChecks(ctx context.Context, cp checksProvider, pr *PullRequest) {
+	state, err := cp.GetChecksState(ctx, pr)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		logger.Shared().Printf("error getting checks for [%s/%s/%d] %s\n", pr.OrgName, pr.RepoName, pr.Number, err)
+		tracking.SendMetric("data.getchecks.error")
+		ds.storePull(pr)
+		return
+	}
+	updated := *pr
+	updated.ChecksState = state
+	ds.storePull(&updated)
+}
+
+func (ds *Datasource) pullsForRepo(providerName string, orgName string, repoName string) map[string]*PullRequest {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	prs := map[string]*PullRequest{}
+	for id, pr := range ds.allPRs {
+		if pr.Provider == providerName && pr.OrgName == orgName && pr.RepoName == repoName {
+			prs[id] = pr
+		}
+	}
+	return prs
+}
+
+func (ds *Datasource) buildPr(ctx context.Context, provider Provider, pr *PullRequest) bool {
+	if !pr.hydrated {
+		err := provider.HydratePull(ctx, pr)
+		if ctx.Err() != nil {
+			return false
+		}
 		if err != nil {
 			ds.writeErrorStatus(err)
 			logger.Shared().Printf("%s\n", err)
 			tracking.SendMetric("data.buildpr.error")
-			return
+			return false
 		}
+	}
+	ds.storePull(pr)
+	ds.statusChan <- "" // clear status after each PR
+	return true
+}
+
+// Scores a copy of the PR, stores it and sends it to the views. Stored PRs
+// are read by the views and the api without a lock so they are replaced
+// rather than modified.
+func (ds *Datasource) storePull(pr *PullRequest) {
+	scored := *pr
+	scored.calculateStatusFields(ds)
+	scored.calculateImportance(ds)
+
+	ds.mutex.Lock()
+	ds.allPRs[scored.ID] = &scored
+	ds.mutex.Unlock()
+	ds.prUpdateChan <- &scored
+}
+
+// Drops the PR from the cache. A copy is still sent to the views, flagged
+// as closed, so they can remove it.
+func (ds *Datasource) removePull(pr *PullRequest) {
+	ds.mutex.Lock()
+	delete(ds.allPRs, pr.ID)
+	ds.mutex.Unlock()
+
+	closed := *pr
+	closed.IsClosed = true
+	ds.prUpdateChan <- &closed
+}
+
+func (ds *Datasource) NumPulls() int {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	return len(ds.allPRs)
+}
+
+// Every cached PR in no particular order, measured up to now
+func (ds *Datasource) GetPulls() []*PullRequest {
+	pulls := ds.storedPulls()
+	for i, pr := range pulls {
+		pulls[i] = ds.current(pr)
+	}
+	return pulls
+}
+
+func (ds *Datasource) storedPulls() []*PullRequest {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	pulls := make([]*PullRequest, 0, len(ds.allPRs))
+	for _, pr := range ds.allPRs {
+		pulls = append(pulls, pr)
+	}
+	return pulls
+}
+
+// Working hours from t until now, or wall time when no working hours are
+// configured
+func (ds *Datasource) BusinessTimeSince(t time.Time) time.Duration {
+	return ds.calendar.since(t)
+}
+
+// A copy of the PR with its time based fields and score brought up to now.
+// Stored PRs are only scored when they are fetched.
+func (ds *Datasource) current(pr *PullRequest) *PullRequest {
+	cur := *pr
+	cur.calculateTimeFields(ds, time.Now())
+	cur.calculateImportance(ds)
+	return &cur
+}
+
+// Brings every stored PR up to now and sends it to the views so filters on
+// elapsed time, like the Active tab, keep matching between refreshes
+func (ds *Datasource) RescorePulls() {
+	for _, pr := range ds.storedPulls() {
+		cur := ds.current(pr)
+
 		ds.mutex.Lock()
-		ds.allPRs[newPR.PR.GetNodeID()] = newPR
+		// a refresh may have replaced or removed it in the meantime
+		replaced := ds.allPRs[pr.ID] == pr
+		if replaced {
+			ds.allPRs[pr.ID] = cur
+		}
 		ds.mutex.Unlock()
-		ds.prUpdateChan <- newPR
-	}
 
-	ds.SaveToFile()
-	ds.statusChan <- "" // clear status after each PR
+		if replaced {
+			ds.prUpdateChan <- cur
+		}
+	}
 }
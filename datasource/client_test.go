@@ -0,0 +1,314 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+)
+
+// fakeProvider serves one repo. The first listing blocks until its refresh
+// is cancelled and then returns anyway, like a slow response that ignores
+// the context.
+type fakeProvider struct {
+	started chan struct{}
+
+	mutex sync.Mutex
+	calls int
+}
+
+func (f *fakeProvider) Name() string     { return GithubProviderName }
+func (f *fakeProvider) Username() string { return "me" }
+
+func (f *fakeProvider) GetAllOrgs(ctx context.Context) ([]string, error) {
+	return []string{"org"}, nil
+}
+
+func (f *fakeProvider) GetAllReposForOrg(ctx context.Context, orgName string) ([]string, error) {
+	return []string{"repo"}, nil
+}
+
+func (f *fakeProvider) GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error) {
+	f.mutex.Lock()
+	f.calls++
+	first := f.calls == 1
+	f.mutex.Unlock()
+
+	if first {
+		close(f.started)
+		<-ctx.Done()
+		return []*PullRequest{f.pull("stale")}, nil
+	}
+	return []*PullRequest{f.pull("fresh")}, nil
+}
+
+func (f *fakeProvider) HydratePull(ctx context.Context, pr *PullRequest) error {
+	return nil
+}
+
+func (f *fakeProvider) pull(id string) *PullRequest {
+	return &PullRequest{
+		Provider:  GithubProviderName,
+		ID:        id,
+		OrgName:   "org",
+		RepoName:  "repo",
+		UpdatedAt: time.Now(),
+		hydrated:  true,
+	}
+}
+
+// staticProvider lists the same PRs every time and reports their checks
+type staticProvider struct {
+	fakeProvider
+	listed []*PullRequest
+	checks string
+}
+
+func (f *staticProvider) GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error) {
+	return f.listed, nil
+}
+
+func (f *staticProvider) GetChecksState(ctx context.Context, pr *PullRequest) (string, error) {
+	return f.checks, nil
+}
+
+var _ = Describe("Datasource", func() {
+	var (
+		ds       *Datasource
+		provider *fakeProvider
+
+		mutex   sync.Mutex
+		updated []string
+	)
+
+	BeforeEach(func() {
+		ds = New(&config.Config{GithubUsername: "me", RefreshWorkers: 2})
+		provider = &fakeProvider{started: make(chan struct{})}
+		ds.providers = []Provider{provider}
+
+		statusChan := make(chan string)
+		prUpdateChan := make(chan *PullRequest)
+		remainingRequestsChan := make(chan github.Rate)
+		ds.SetStatusChan(statusChan)
+		ds.SetPRUpdateChan(prUpdateChan)
+		ds.SetRemainingRequestsChan(remainingRequestsChan)
+
+		mutex.Lock()
+		updated = []string{}
+		mutex.Unlock()
+		go func() {
+			for range statusChan {
+			}
+		}()
+		go func() {
+			for pr := range prUpdateChan {
+				mutex.Lock()
+				updated = append(updated, pr.ID)
+				mutex.Unlock()
+			}
+		}()
+		go func() {
+			for range remainingRequestsChan {
+			}
+		}()
+	})
+
+	storedPull := func(id string) *PullRequest {
+		ds.mutex.RLock()
+		defer ds.mutex.RUnlock()
+		return ds.allPRs[id]
+	}
+
+	Context("RefreshData", func() {
+		It("should cancel the refresh in flight and keep none of its PRs", func() {
+			first := make(chan struct{})
+			go func() {
+				ds.RefreshData()
+				close(first)
+			}()
+			Eventually(provider.started).Should(BeClosed())
+
+			ds.RefreshData()
+			Eventually(first).Should(BeClosed())
+			Expect(ds.IsCurrentlyRefreshingData()).To(BeFalse())
+
+			ids := []string{}
+			for _, pr := range ds.GetPulls() {
+				ids = append(ids, pr.ID)
+			}
+			Expect(ids).To(Equal([]string{"fresh"}))
+			// the last send can land just after the refresh returns
+			Eventually(func() []string {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return append([]string{}, updated...)
+			}).Should(Equal([]string{"fresh"}))
+		})
+	})
+
+	Context("LoadLocalCache", func() {
+		var cachePath string
+
+		BeforeEach(func() {
+			fetched := time.Now().Add(-72 * time.Hour)
+			cached := map[string]*PullRequest{
+				"a": {
+					Provider: GithubProviderName, ID: "a", OrgName: "org", RepoName: "repo",
+					CreatedAt: fetched, LastCommitTime: fetched, LastCommentTime: fetched,
+					Comments: []*Comment{
+						{ID: "1", Author: "alice", Path: "main.go", Line: 3, CreatedAt: fetched},
+						{ID: "2", Author: "me", InReplyTo: "1", Path: "main.go", Line: 3, CreatedAt: fetched},
+					},
+				},
+			}
+			data, err := json.Marshal(cached)
+			Expect(err).NotTo(HaveOccurred())
+			cachePath, err = config.GetPRCacheFilePath()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(cachePath, data, 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Remove(cachePath)
+		})
+
+		It("should rebuild what is not saved and measure up to now", func() {
+			ds.LoadLocalCache()
+
+			pr := storedPull("a")
+			Expect(pr.Activity).To(HaveLen(2))
+			Expect(pr.Threads).To(HaveLen(1))
+			Expect(pr.Threads[0].Comments).To(HaveLen(2))
+			Expect(pr.Threads[0].LastReplyFromMe).To(BeTrue())
+			Expect(pr.TimeSinceLastActivity).To(BeNumerically("~", 72*time.Hour, time.Minute))
+			Eventually(func() []string {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return append([]string{}, updated...)
+			}).Should(Equal([]string{"a"}))
+		})
+	})
+
+	Context("storePull", func() {
+		It("should replace the stored PR rather than rescore it in place", func() {
+			ds.storePull(provider.pull("a"))
+			stored := storedPull("a")
+			lookup := stored.ImportanceLookup
+			importance := stored.Importance
+
+			// the views and api read stored PRs while a refresh rescores them
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range lookup {
+				}
+			}()
+			ds.storePull(stored)
+			<-done
+
+			Expect(storedPull("a")).NotTo(BeIdenticalTo(stored))
+			Expect(stored.ImportanceLookup).To(Equal(lookup))
+			Expect(stored.Importance).To(Equal(importance))
+		})
+	})
+
+	Context("time based fields", func() {
+		var stored *PullRequest
+
+		BeforeEach(func() {
+			// scored when it was fetched three days ago
+			fetched := provider.pull("a")
+			fetched.CreatedAt = time.Now().Add(-72 * time.Hour)
+			fetched.calculateStatusFields(ds)
+			fetched.calculateTimeFields(ds, fetched.CreatedAt)
+			stored = fetched
+			ds.allPRs["a"] = stored
+		})
+
+		It("should measure the PRs it returns up to now", func() {
+			pr := ds.GetPulls()[0]
+			Expect(pr.TimeSinceLastActivity).To(BeNumerically("~", 72*time.Hour, time.Minute))
+			Expect(stored.TimeSinceLastActivity).To(BeZero())
+
+			// the Active tab
+			active, err := FilterPulls(DefaultTabs[4], ds.GetPulls())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(active).To(BeEmpty())
+		})
+
+		It("should not share the breakdown of the stored PRs", func() {
+			stored.calculateImportance(ds)
+			pr := ds.GetPulls()[0]
+			pr.ImportanceLookup["Made up"] = 1
+			Expect(stored.ImportanceLookup).NotTo(HaveKey("Made up"))
+		})
+
+		It("should drop the score of PRs abandoned since they were fetched", func() {
+			stored.CreatedAt = time.Now().AddDate(0, 0, -DefaultAbandonedAgeDays-1)
+			stored.LastCommitTime = stored.CreatedAt
+			stored.Importance = 50
+
+			pr := ds.GetPulls()[0]
+			Expect(pr.IsAbandoned).To(BeTrue())
+			Expect(pr.Importance).To(BeZero())
+		})
+
+		It("should replace and send the stored PRs on a rescore", func() {
+			ds.RescorePulls()
+
+			rescored := storedPull("a")
+			Expect(rescored).NotTo(BeIdenticalTo(stored))
+			Expect(rescored.TimeSinceLastActivity).To(BeNumerically("~", 72*time.Hour, time.Minute))
+			Eventually(func() []string {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return append([]string{}, updated...)
+			}).Should(Equal([]string{"a"}))
+		})
+	})
+
+	Context("refreshRepo", func() {
+		var (
+			static *staticProvider
+			cached *PullRequest
+		)
+
+		BeforeEach(func() {
+			static = &staticProvider{}
+			ds.providers = []Provider{static}
+
+			cached = static.pull("a")
+			cached.ChecksState = CheckStatePending
+			ds.allPRs["a"] = cached
+			ds.repoWatermarks["github/org/repo"] = cached.UpdatedAt
+		})
+
+		It("should re-read the checks of unchanged PRs", func() {
+			listed := *cached
+			static.listed = []*PullRequest{&listed}
+			static.checks = CheckStateFailure
+			ds.RefreshData()
+
+			pr := ds.GetPulls()[0]
+			Expect(pr.ChecksState).To(Equal(CheckStateFailure))
+			Expect(pr.ChecksFailing).To(BeTrue())
+			Expect(pr.ChecksPending).To(BeFalse())
+		})
+
+		It("should re-read the checks of PRs older than the watermark", func() {
+			static.checks = CheckStateSuccess
+			ds.RefreshData()
+
+			pr := ds.GetPulls()[0]
+			Expect(pr.ChecksPassing).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package datasource
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Locations github checks for a CODEOWNERS file, first found wins
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners is a parsed CODEOWNERS file
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// Parses the gitignore style CODEOWNERS format. Lines that can not be
+// parsed are skipped the same way github skips them.
+func ParseCodeOwners(content string) *CodeOwners {
+	c := &CodeOwners{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// trailing comments
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		pattern, err := regexp.Compile(codeOwnersPatternToRegexp(fields[0]))
+		if err != nil {
+			continue
+		}
+		c.rules = append(c.rules, codeOwnersRule{
+			pattern: pattern,
+			owners:  fields[1:],
+		})
+	}
+	return c
+}
+
+// Owners of the path. The last matching rule wins, a rule with no owners
+// means the path is explicitly unowned.
+func (c *CodeOwners) OwnersOf(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(path) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+func codeOwnersPatternToRegexp(pattern string) string {
+	// a slash anywhere but the end anchors the pattern to the repo root,
+	// otherwise it matches at any depth
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" matches zero or more directories
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	lastSegment := pattern[strings.LastIndex(pattern, "/")+1:]
+	if dirOnly {
+		expr.WriteString("/.*")
+	} else if !strings.Contains(lastSegment, "*") {
+		// a name matches the file or everything under a directory with that
+		// name. "docs/*" only matches files directly in docs.
+		expr.WriteString("(/.*)?")
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
@@ -0,0 +1,53 @@
+package datasource
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CodeOwners", func() {
+	Describe("OwnersOf", func() {
+		owners := ParseCodeOwners(`
+# default owners
+*       @org/everyone
+
+*.js    @js-owner # trailing comment
+/build/ @org/build
+docs/*  @docs-owner
+apps/** @apps-owner
+**/logs @logs-owner
+/vendor
+`)
+
+		It("should match any path with a bare wildcard", func() {
+			Expect(owners.OwnersOf("README.md")).To(Equal([]string{"@org/everyone"}))
+		})
+
+		It("should match unanchored patterns at any depth", func() {
+			Expect(owners.OwnersOf("web/src/app.js")).To(Equal([]string{"@js-owner"}))
+		})
+
+		It("should match everything under a directory pattern", func() {
+			Expect(owners.OwnersOf("build/scripts/release.sh")).To(Equal([]string{"@org/build"}))
+			Expect(owners.OwnersOf("src/build/main.go")).To(Equal([]string{"@org/everyone"}))
+		})
+
+		It("should only match direct children with a single star", func() {
+			Expect(owners.OwnersOf("docs/intro.md")).To(Equal([]string{"@docs-owner"}))
+			Expect(owners.OwnersOf("docs/guides/intro.md")).To(Equal([]string{"@org/everyone"}))
+		})
+
+		It("should match nested paths with a double star", func() {
+			Expect(owners.OwnersOf("apps/web/index.html")).To(Equal([]string{"@apps-owner"}))
+			Expect(owners.OwnersOf("deep/nested/logs/out.txt")).To(Equal([]string{"@logs-owner"}))
+		})
+
+		It("should let the last matching rule win", func() {
+			Expect(owners.OwnersOf("apps/web/index.js")).To(Equal([]string{"@apps-owner"}))
+		})
+
+		It("should treat a rule without owners as unowned", func() {
+			Expect(owners.OwnersOf("vendor/lib/lib.go")).To(BeEmpty())
+		})
+	})
+})
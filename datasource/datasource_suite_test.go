@@ -0,0 +1,32 @@
+package datasource
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/logger"
+)
+
+func TestDatasource(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Datasource Suite")
+}
+
+// the log and cache files go to a throwaway home
+var home string
+
+var _ = BeforeSuite(func() {
+	var err error
+	home, err = ioutil.TempDir("", "prty-home")
+	Expect(err).NotTo(HaveOccurred())
+	os.Setenv("HOME", home)
+	Expect(logger.InitializeLogger()).To(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	os.RemoveAll(home)
+})
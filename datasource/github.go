@@ -0,0 +1,581 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/inburst/prty/logger"
+)
+
+// githubProvider talks to the github REST v3 api through the shared client
+type githubProvider struct {
+	ds       *Datasource
+	username string
+}
+
+func newGithubProvider(ds *Datasource, username string) *githubProvider {
+	return &githubProvider{
+		ds:       ds,
+		username: username,
+	}
+}
+
+func (g *githubProvider) Name() string {
+	return GithubProviderName
+}
+
+func (g *githubProvider) Username() string {
+	return g.username
+}
+
+func (g *githubProvider) GetAllOrgs(ctx context.Context) ([]string, error) {
+	orgs, err := g.listOrgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, o := range orgs {
+		if o.Login != nil {
+			names = append(names, o.GetLogin())
+		}
+	}
+	return names, nil
+}
+
+func (g *githubProvider) GetAllReposForOrg(ctx context.Context, orgName string) ([]string, error) {
+	repos, err := g.listReposForOrg(ctx, orgName)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, r := range repos {
+		names = append(names, r.GetName())
+	}
+	return names, nil
+}
+
+func (g *githubProvider) GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error) {
+	opt := &github.PullRequestListOptions{
+		ListOptions: github.ListOptions{PerPage: 10},
+		State:       "open",
+		Sort:        "updated",
+		Direction:   "desc",
+	}
+	// closing a PR bumps updated_at so it will show up before the cutoff
+	if !since.IsZero() {
+		opt.State = "all"
+	}
+	// page through to get all pulls for this repo
+	// NOTE: the returned PR data is not complete and needs to be directly fetched
+	// to hydrate fields like additions and deletions
+	var allPulls []*PullRequest
+	for {
+		logger.Shared().Printf("getting pulls for: [%s/%s] page:%d", orgName, repoName, opt.Page)
+		var prs []*github.PullRequest
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			prs, resp, err = sharedClient().PullRequests.List(ctx, orgName, repoName, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("error getting pulls in [%s/%s] %s", orgName, repoName, err)
+			return allPulls, err
+		}
+
+		reachedSince := false
+		for _, ghpr := range prs {
+			pr := &PullRequest{
+				Provider: GithubProviderName,
+				OrgName:  orgName,
+				RepoName: repoName,
+			}
+			applyGithubPull(pr, ghpr)
+			if !since.IsZero() && !pr.UpdatedAt.After(since) {
+				reachedSince = true
+				break
+			}
+			allPulls = append(allPulls, pr)
+		}
+		if reachedSince || resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allPulls, nil
+}
+
+func (g *githubProvider) HydratePull(ctx context.Context, pr *PullRequest) error {
+	org, repo, number := pr.OrgName, pr.RepoName, pr.Number
+
+	// PRs that are sourced from the /list api will not contain all desired
+	// fields including additions and deletions
+	fullGHPR, err := g.GetPull(ctx, org, repo, number)
+	if err != nil {
+		return err
+	}
+	applyGithubPull(pr, fullGHPR)
+
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching commits...", org, repo, number))
+	commits, lastCommitsPage, err := g.GetAllCommitsForPull(ctx, org, repo, number, pr.LastCommitsPage)
+	if err != nil {
+		return err
+	}
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching comments...", org, repo, number))
+	comments, lastCommentsPage, err := g.GetAllCommentsForPull(ctx, org, repo, number, pr.LastCommentsPage)
+	if err != nil {
+		return err
+	}
+	issueComments, lastIssueCommentsPage, err := g.GetAllIssueCommentsForPull(ctx, org, repo, number, pr.LastIssueCommentsPage)
+	if err != nil {
+		return err
+	}
+	// the REST api has no notion of resolved threads
+	resolved, outdated, err := g.GetThreadStatesForPull(ctx, org, repo, number)
+	if err != nil {
+		return err
+	}
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching reviews...", org, repo, number))
+	reviews, lastReviewsPage, err := g.GetAllReviewsForPull(ctx, org, repo, number, pr.LastReviewsPage)
+	if err != nil {
+		return err
+	}
+
+	// files, like checks, follow the head commit so they are always fetched in full
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching files...", org, repo, number))
+	files, err := g.GetAllFilesForPull(ctx, org, repo, number)
+	if err != nil {
+		return err
+	}
+	pr.ChangedFiles = []string{}
+	for _, f := range files {
+		pr.ChangedFiles = append(pr.ChangedFiles, f.Path)
+	}
+
+	// checks are per head commit so they are always fetched in full
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching checks...", org, repo, number))
+	checksState, err := g.GetChecksStateForRef(ctx, org, repo, pr.HeadSHA)
+	if err != nil {
+		return err
+	}
+	pr.ChecksState = checksState
+
+	pr.Commits = mergeCommits(pr.Commits, commits)
+	pr.Comments = mergeComments(pr.Comments, comments)
+	pr.IssueComments = mergeComments(pr.IssueComments, issueComments)
+	pr.Reviews = mergeReviews(pr.Reviews, reviews)
+	pr.ResolvedThreads = resolved
+	pr.OutdatedThreads = outdated
+
+	pr.LastCommitsPage = lastCommitsPage
+	pr.LastCommentsPage = lastCommentsPage
+	pr.LastIssueCommentsPage = lastIssueCommentsPage
+	pr.LastReviewsPage = lastReviewsPage
+	return nil
+}
+
+// Copies the metadata of a github PR onto our envelope
+func applyGithubPull(pr *PullRequest, ghpr *github.PullRequest) {
+	pr.ID = ghpr.GetNodeID()
+	pr.Number = ghpr.GetNumber()
+	pr.Title = ghpr.GetTitle()
+	pr.Body = ghpr.GetBody()
+	pr.URL = ghpr.GetHTMLURL()
+	pr.HeadSHA = ghpr.GetHead().GetSHA()
+	pr.BaseBranch = ghpr.GetBase().GetRef()
+	pr.Author = ghpr.GetUser().GetLogin()
+	pr.IsDraft = ghpr.GetDraft()
+	pr.CreatedAt = ghpr.GetCreatedAt().Time
+	pr.UpdatedAt = ghpr.GetUpdatedAt().Time
+	pr.IsClosed = ghpr.GetState() != "open"
+
+	// additions, deletions and mergeability are only present on the full PR
+	if ghpr.Additions != nil || ghpr.Deletions != nil {
+		pr.Additions = ghpr.GetAdditions()
+		pr.Deletions = ghpr.GetDeletions()
+	}
+	if ghpr.MergeableState != nil {
+		pr.HasConflicts = ghpr.GetMergeableState() == "dirty"
+	}
+
+	pr.Labels = []string{}
+	for _, l := range ghpr.Labels {
+		pr.Labels = append(pr.Labels, l.GetName())
+	}
+
+	pr.RequestedReviewers = []string{}
+	for _, r := range ghpr.RequestedReviewers {
+		pr.RequestedReviewers = append(pr.RequestedReviewers, r.GetLogin())
+	}
+
+	pr.RequestedTeams = []string{}
+	for _, t := range ghpr.RequestedTeams {
+		pr.RequestedTeams = append(pr.RequestedTeams, fmt.Sprintf("%s/%s", pr.OrgName, t.GetSlug()))
+	}
+}
+
+func (g *githubProvider) GetPull(ctx context.Context, org string, repo string, prNumber int) (*github.PullRequest, error) {
+	var fullPR *github.PullRequest
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		fullPR, resp, err = sharedClient().PullRequests.Get(ctx, org, repo, prNumber)
+		return
+	})
+	return fullPR, err
+}
+
+func (g *githubProvider) GetAllCommitsForPull(ctx context.Context, org string, repo string, prNumber int, lastPage int) ([]*Commit, int, error) {
+	opt := &github.ListOptions{
+		PerPage: 10,
+		Page:    lastPage,
+	}
+	// get all pages of results
+	var allCommits []*Commit
+	for {
+		logger.Shared().Printf("commits: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
+		var commits []*github.RepositoryCommit
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			commits, resp, err = sharedClient().PullRequests.ListCommits(ctx, org, repo, prNumber, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("commits: error %s", err)
+			return allCommits, lastPage, err
+		}
+		for _, c := range commits {
+			allCommits = append(allCommits, &Commit{
+				ID:          c.GetSHA(),
+				SHA:         c.GetSHA(),
+				Author:      c.GetAuthor().GetLogin(),
+				CommittedAt: c.GetCommit().GetCommitter().GetDate().Time,
+			})
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+		lastPage = resp.NextPage
+	}
+	return allCommits, lastPage, nil
+}
+
+func (g *githubProvider) GetAllCommentsForPull(ctx context.Context, org string, repo string, prNumber int, lastPage int) ([]*Comment, int, error) {
+	opt := &github.PullRequestListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 10,
+			Page:    lastPage,
+		},
+	}
+	// get all pages of results
+	var allComments []*Comment
+	for {
+		logger.Shared().Printf("comments: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
+		var comments []*github.PullRequestComment
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			comments, resp, err = sharedClient().PullRequests.ListComments(ctx, org, repo, prNumber, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("comments: error %s", err)
+			return allComments, lastPage, err
+		}
+		for _, c := range comments {
+			comment := &Comment{
+				ID:        strconv.FormatInt(c.GetID(), 10),
+				Author:    c.GetUser().GetLogin(),
+				Body:      c.GetBody(),
+				Path:      c.GetPath(),
+				CreatedAt: c.GetCreatedAt().Time,
+				Line:      c.GetLine(),
+				DiffHunk:  c.GetDiffHunk(),
+			}
+			// outdated comments no longer have a line in the current diff
+			if c.Line == nil {
+				comment.Line = c.GetOriginalLine()
+			}
+			if c.InReplyTo != nil {
+				comment.InReplyTo = strconv.FormatInt(c.GetInReplyTo(), 10)
+			}
+			allComments = append(allComments, comment)
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+		lastPage = resp.NextPage
+	}
+	return allComments, lastPage, nil
+}
+
+// Comments left on the conversation tab are issue comments in the github api
+func (g *githubProvider) GetAllIssueCommentsForPull(ctx context.Context, org string, repo string, prNumber int, lastPage int) ([]*Comment, int, error) {
+	opt := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 10,
+			Page:    lastPage,
+		},
+	}
+	// get all pages of results
+	var allComments []*Comment
+	for {
+		logger.Shared().Printf("issue comments: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
+		var comments []*github.IssueComment
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			comments, resp, err = sharedClient().Issues.ListComments(ctx, org, repo, prNumber, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("issue comments: error %s", err)
+			return allComments, lastPage, err
+		}
+		for _, c := range comments {
+			allComments = append(allComments, &Comment{
+				ID:        strconv.FormatInt(c.GetID(), 10),
+				Author:    c.GetUser().GetLogin(),
+				Body:      c.GetBody(),
+				CreatedAt: c.GetCreatedAt().Time,
+			})
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+		lastPage = resp.NextPage
+	}
+	return allComments, lastPage, nil
+}
+
+func (g *githubProvider) GetAllReviewsForPull(ctx context.Context, org string, repo string, prNumber int, lastPage int) ([]*Review, int, error) {
+	opt := &github.ListOptions{
+		PerPage: 10,
+		Page:    lastPage,
+	}
+	// get all pages of results
+	var allReviews []*Review
+	for {
+		logger.Shared().Printf("reviews: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
+		var reviews []*github.PullRequestReview
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			reviews, resp, err = sharedClient().PullRequests.ListReviews(ctx, org, repo, prNumber, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("reviews: error %s", err)
+			return allReviews, lastPage, err
+		}
+		for _, r := range reviews {
+			allReviews = append(allReviews, &Review{
+				ID:          strconv.FormatInt(r.GetID(), 10),
+				Author:      r.GetUser().GetLogin(),
+				State:       r.GetState(),
+				Body:        r.GetBody(),
+				CommitSHA:   r.GetCommitID(),
+				SubmittedAt: r.GetSubmittedAt().Time,
+			})
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+		lastPage = resp.NextPage
+	}
+	return allReviews, lastPage, nil
+}
+
+func (g *githubProvider) GetAllFilesForPull(ctx context.Context, org string, repo string, prNumber int) ([]*FileDiff, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	// get all pages of results
+	allFiles := []*FileDiff{}
+	for {
+		logger.Shared().Printf("files: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
+		var files []*github.CommitFile
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			files, resp, err = sharedClient().PullRequests.ListFiles(ctx, org, repo, prNumber, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("files: error %s", err)
+			return allFiles, err
+		}
+		for _, f := range files {
+			allFiles = append(allFiles, &FileDiff{
+				Path:         f.GetFilename(),
+				PreviousPath: f.GetPreviousFilename(),
+				Status:       f.GetStatus(),
+				Additions:    f.GetAdditions(),
+				Deletions:    f.GetDeletions(),
+				Patch:        f.GetPatch(),
+			})
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allFiles, nil
+}
+
+func (g *githubProvider) GetChecksState(ctx context.Context, pr *PullRequest) (string, error) {
+	return g.GetChecksStateForRef(ctx, pr.OrgName, pr.RepoName, pr.HeadSHA)
+}
+
+// Github has two CI apis, the older commit statuses and check runs. Both are
+// read and reduced to a single state.
+func (g *githubProvider) GetChecksStateForRef(ctx context.Context, org string, repo string, ref string) (string, error) {
+	states := []string{}
+
+	logger.Shared().Printf("status: %s/%s@%s", org, repo, ref)
+	var status *github.CombinedStatus
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		status, resp, err = sharedClient().Repositories.GetCombinedStatus(ctx, org, repo, ref, &github.ListOptions{PerPage: 100})
+		return
+	})
+	if err != nil {
+		logger.Shared().Printf("status: error %s", err)
+		return "", err
+	}
+	// the combined state is pending when there are no statuses at all
+	if status.GetTotalCount() > 0 {
+		switch status.GetState() {
+		case "success":
+			states = append(states, CheckStateSuccess)
+		case "pending":
+			states = append(states, CheckStatePending)
+		default:
+			states = append(states, CheckStateFailure)
+		}
+	}
+
+	opt := &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		logger.Shared().Printf("check runs: %s/%s@%s p:%d", org, repo, ref, opt.Page)
+		var runs *github.ListCheckRunsResults
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			runs, resp, err = sharedClient().Checks.ListCheckRunsForRef(ctx, org, repo, ref, opt)
+			return
+		})
+		if err != nil {
+			logger.Shared().Printf("check runs: error %s", err)
+			return "", err
+		}
+		for _, run := range runs.CheckRuns {
+			states = append(states, githubCheckRunState(run))
+		}
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return combineCheckStates(states...), nil
+}
+
+func githubCheckRunState(run *github.CheckRun) string {
+	if run.GetStatus() != "completed" {
+		return CheckStatePending
+	}
+	switch run.GetConclusion() {
+	case "success", "neutral", "skipped":
+		return CheckStateSuccess
+	default:
+		return CheckStateFailure
+	}
+}
+
+func (g *githubProvider) GetCodeOwners(ctx context.Context, orgName string, repoName string) (*CodeOwners, error) {
+	for _, path := range codeOwnersPaths {
+		logger.Shared().Printf("codeowners: %s/%s/%s", orgName, repoName, path)
+		var file *github.RepositoryContent
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			file, _, resp, err = sharedClient().Repositories.GetContents(ctx, orgName, repoName, path, nil)
+			return
+		})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, err
+		}
+		return ParseCodeOwners(content), nil
+	}
+	return nil, nil
+}
+
+func (g *githubProvider) SubmitReview(ctx context.Context, pr *PullRequest, event string, body string) error {
+	org, repo, number := pr.OrgName, pr.RepoName, pr.Number
+
+	// a top level comment belongs to the conversation, not a review
+	if event == ReviewEventComment {
+		_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			_, resp, err = sharedClient().Issues.CreateComment(ctx, org, repo, number, &github.IssueComment{
+				Body: github.String(body),
+			})
+			return
+		})
+		return err
+	}
+
+	review := &github.PullRequestReviewRequest{Event: github.String(event)}
+	if len(body) > 0 {
+		review.Body = github.String(body)
+	}
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		_, resp, err = sharedClient().PullRequests.CreateReview(ctx, org, repo, number, review)
+		return
+	})
+	return err
+}
+
+func (g *githubProvider) GetFileDiffs(ctx context.Context, pr *PullRequest) ([]*FileDiff, error) {
+	return g.GetAllFilesForPull(ctx, pr.OrgName, pr.RepoName, pr.Number)
+}
+
+func (g *githubProvider) GetRequiredApprovals(ctx context.Context, orgName string, repoName string, branch string) (int, error) {
+	var protection *github.Protection
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		protection, resp, err = sharedClient().Repositories.GetBranchProtection(ctx, orgName, repoName, branch)
+		return
+	})
+	if err == github.ErrBranchNotProtected {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	reviews := protection.GetRequiredPullRequestReviews()
+	if reviews == nil {
+		return 0, nil
+	}
+	return reviews.RequiredApprovingReviewCount, nil
+}
+
+func (g *githubProvider) GetMyTeams(ctx context.Context) ([]string, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	// get all pages of results
+	allTeams := []string{}
+	for {
+		var teams []*github.Team
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			teams, resp, err = sharedClient().Teams.ListUserTeams(ctx, opt)
+			return
+		})
+		if err != nil {
+			return allTeams, err
+		}
+		for _, t := range teams {
+			allTeams = append(allTeams, fmt.Sprintf("%s/%s", t.GetOrganization().GetLogin(), t.GetSlug()))
+		}
+		logger.Shared().Printf("found teams: count:%d\n", len(teams))
+		if resp.NextPage == 0 || opt.Page == resp.NextPage {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allTeams, nil
+}
@@ -0,0 +1,529 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inburst/prty/logger"
+)
+
+const DefaultGitlabBaseURL = "https://gitlab.com"
+
+// gitlabProvider maps gitlab groups, projects and merge requests onto orgs,
+// repos and pull requests using the v4 REST api
+type gitlabProvider struct {
+	ds       *Datasource
+	username string
+	token    string
+	baseURL  string
+	client   *http.Client
+}
+
+func newGitlabProvider(ds *Datasource, baseURL string, token string, username string) *gitlabProvider {
+	if len(baseURL) == 0 {
+		baseURL = DefaultGitlabBaseURL
+	}
+	return &gitlabProvider{
+		ds:       ds,
+		username: username,
+		token:    token,
+		baseURL:  strings.TrimSuffix(baseURL, "/") + "/api/v4",
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabGroup struct {
+	ID       int    `json:"id"`
+	FullPath string `json:"full_path"`
+}
+
+type gitlabProject struct {
+	ID   int    `json:"id"`
+	Path string `json:"path"`
+}
+
+type gitlabMergeRequest struct {
+	ID           int          `json:"id"`
+	IID          int          `json:"iid"`
+	Title        string       `json:"title"`
+	Description  string       `json:"description"`
+	WebURL       string       `json:"web_url"`
+	SHA          string       `json:"sha"`
+	TargetBranch string       `json:"target_branch"`
+	State        string       `json:"state"`
+	HasConflicts bool         `json:"has_conflicts"`
+	Draft        bool         `json:"draft"`
+	WIP          bool         `json:"work_in_progress"`
+	Author       gitlabUser   `json:"author"`
+	Labels       []string     `json:"labels"`
+	Reviewers    []gitlabUser `json:"reviewers"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+
+	// only present when fetching a single merge request
+	HeadPipeline *struct {
+		Status string `json:"status"`
+	} `json:"head_pipeline"`
+}
+
+type gitlabCommit struct {
+	ID            string    `json:"id"`
+	AuthorName    string    `json:"author_name"`
+	CommittedDate time.Time `json:"committed_date"`
+}
+
+type gitlabNote struct {
+	ID        int        `json:"id"`
+	Body      string     `json:"body"`
+	Author    gitlabUser `json:"author"`
+	CreatedAt time.Time  `json:"created_at"`
+	System    bool       `json:"system"`
+	Resolved  bool       `json:"resolved"`
+	Position  *struct {
+		NewPath string `json:"new_path"`
+		NewLine int    `json:"new_line"`
+		OldLine int    `json:"old_line"`
+	} `json:"position"`
+}
+
+// A discussion is a thread of notes, single comments are discussions of one
+type gitlabDiscussion struct {
+	ID    string        `json:"id"`
+	Notes []*gitlabNote `json:"notes"`
+}
+
+// body of the system note left when someone approves
+const gitlabApprovedNote = "approved this merge request"
+
+type gitlabApprovals struct {
+	ApprovedBy []struct {
+		User gitlabUser `json:"user"`
+	} `json:"approved_by"`
+}
+
+type gitlabProjectApprovals struct {
+	ApprovalsBeforeMerge int `json:"approvals_before_merge"`
+}
+
+type gitlabChanges struct {
+	Changes []struct {
+		OldPath     string `json:"old_path"`
+		NewPath     string `json:"new_path"`
+		NewFile     bool   `json:"new_file"`
+		DeletedFile bool   `json:"deleted_file"`
+		RenamedFile bool   `json:"renamed_file"`
+		Diff        string `json:"diff"`
+	} `json:"changes"`
+}
+
+func (g *gitlabProvider) Name() string {
+	return GitlabProviderName
+}
+
+func (g *gitlabProvider) Username() string {
+	return g.username
+}
+
+// Performs a GET against the api and decodes the json body into v. Returns
+// the next page number or 0 when there are no more pages.
+func (g *gitlabProvider) get(ctx context.Context, path string, query url.Values, v interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s%s?%s", g.baseURL, path, query.Encode()), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("gitlab GET %s: %s", path, resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, err
+	}
+	nextPage, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return nextPage, nil
+}
+
+// Performs a POST with v encoded as the json body
+func (g *gitlabProvider) post(ctx context.Context, path string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("gitlab POST %s: %s", path, resp.Status)
+	}
+	return nil
+}
+
+func projectPath(orgName string, repoName string) string {
+	return "/projects/" + url.PathEscape(orgName+"/"+repoName)
+}
+
+func (g *gitlabProvider) GetAllOrgs(ctx context.Context) ([]string, error) {
+	query := url.Values{}
+	query.Set("min_access_level", "10")
+	query.Set("per_page", "20")
+
+	// get all pages of results
+	var allGroups []string
+	for {
+		groups := []*gitlabGroup{}
+		nextPage, err := g.get(ctx, "/groups", query, &groups)
+		if err != nil {
+			logger.Shared().Printf("gitlab group err: %s\n", err)
+			return allGroups, err
+		}
+		logger.Shared().Printf("found gitlab groups: count:%d\n", len(groups))
+		for _, group := range groups {
+			allGroups = append(allGroups, group.FullPath)
+		}
+		if nextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(nextPage))
+	}
+	return allGroups, nil
+}
+
+func (g *gitlabProvider) GetAllReposForOrg(ctx context.Context, orgName string) ([]string, error) {
+	query := url.Values{}
+	query.Set("archived", "false")
+	query.Set("per_page", "20")
+
+	// get all pages of results
+	var allProjects []string
+	for {
+		projects := []*gitlabProject{}
+		nextPage, err := g.get(ctx, "/groups/"+url.PathEscape(orgName)+"/projects", query, &projects)
+		if err != nil {
+			logger.Shared().Printf("gitlab projects err for group [%s]: %s\n", orgName, err)
+			return allProjects, err
+		}
+		logger.Shared().Printf("found gitlab projects in group [%s]: count:%d\n", orgName, len(projects))
+		for _, p := range projects {
+			allProjects = append(allProjects, p.Path)
+		}
+		if nextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(nextPage))
+	}
+	return allProjects, nil
+}
+
+func (g *gitlabProvider) GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("order_by", "updated_at")
+	query.Set("sort", "desc")
+	query.Set("per_page", "10")
+	if !since.IsZero() {
+		query.Set("state", "all")
+		query.Set("updated_after", since.UTC().Format(time.RFC3339))
+	}
+
+	var allPulls []*PullRequest
+	for {
+		logger.Shared().Printf("getting merge requests for: [%s/%s] page:%s", orgName, repoName, query.Get("page"))
+		mrs := []*gitlabMergeRequest{}
+		nextPage, err := g.get(ctx, projectPath(orgName, repoName)+"/merge_requests", query, &mrs)
+		if err != nil {
+			logger.Shared().Printf("error getting merge requests in [%s/%s] %s", orgName, repoName, err)
+			return allPulls, err
+		}
+		for _, mr := range mrs {
+			allPulls = append(allPulls, mr.toPullRequest(orgName, repoName))
+		}
+		if nextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(nextPage))
+	}
+	return allPulls, nil
+}
+
+func (mr *gitlabMergeRequest) toPullRequest(orgName string, repoName string) *PullRequest {
+	pr := &PullRequest{
+		Provider:   GitlabProviderName,
+		ID:         fmt.Sprintf("%s/%d", GitlabProviderName, mr.ID),
+		Number:     mr.IID,
+		Title:      mr.Title,
+		Body:       mr.Description,
+		URL:        mr.WebURL,
+		HeadSHA:    mr.SHA,
+		BaseBranch: mr.TargetBranch,
+		Author:     mr.Author.Username,
+		IsDraft:    mr.Draft || mr.WIP,
+		CreatedAt:  mr.CreatedAt,
+		UpdatedAt:  mr.UpdatedAt,
+		IsClosed:   mr.State != "opened",
+
+		HasConflicts: mr.HasConflicts,
+		OrgName:      orgName,
+		RepoName:     repoName,
+
+		Labels:             mr.Labels,
+		RequestedReviewers: []string{},
+		RequestedTeams:     []string{},
+	}
+	for _, r := range mr.Reviewers {
+		pr.RequestedReviewers = append(pr.RequestedReviewers, r.Username)
+	}
+	return pr
+}
+
+// Gitlab endpoints are cheap to page so everything is refetched and merged
+// by id rather than tracking the last page seen.
+func (g *gitlabProvider) HydratePull(ctx context.Context, pr *PullRequest) error {
+	mrPath := fmt.Sprintf("%s/merge_requests/%d", projectPath(pr.OrgName, pr.RepoName), pr.Number)
+
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/!%d fetching pipeline...", pr.OrgName, pr.RepoName, pr.Number))
+	mr := &gitlabMergeRequest{}
+	if _, err := g.get(ctx, mrPath, url.Values{}, mr); err != nil {
+		return err
+	}
+	pr.HasConflicts = mr.HasConflicts
+	pr.ChecksState = ""
+	if mr.HeadPipeline != nil {
+		pr.ChecksState = gitlabPipelineState(mr.HeadPipeline.Status)
+	}
+
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/!%d fetching commits...", pr.OrgName, pr.RepoName, pr.Number))
+	query := url.Values{}
+	query.Set("per_page", "20")
+	for {
+		commits := []*gitlabCommit{}
+		nextPage, err := g.get(ctx, mrPath+"/commits", query, &commits)
+		if err != nil {
+			return err
+		}
+		fetched := []*Commit{}
+		for _, c := range commits {
+			fetched = append(fetched, &Commit{
+				ID:          c.ID,
+				SHA:         c.ID,
+				Author:      c.AuthorName,
+				CommittedAt: c.CommittedDate,
+			})
+		}
+		pr.Commits = mergeCommits(pr.Commits, fetched)
+		if nextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(nextPage))
+	}
+
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/!%d fetching comments...", pr.OrgName, pr.RepoName, pr.Number))
+	query = url.Values{}
+	query.Set("per_page", "20")
+	pr.ResolvedThreads = []string{}
+	pr.OutdatedThreads = []string{}
+	// the approvals api has no timestamps, only the system note does
+	approvedAt := map[string]time.Time{}
+	for {
+		discussions := []*gitlabDiscussion{}
+		nextPage, err := g.get(ctx, mrPath+"/discussions", query, &discussions)
+		if err != nil {
+			return err
+		}
+		diffNotes := []*Comment{}
+		overviewNotes := []*Comment{}
+		for _, d := range discussions {
+			root := ""
+			for _, n := range d.Notes {
+				// system notes are things like "added 1 commit"
+				if n.System {
+					if n.Body == gitlabApprovedNote && n.CreatedAt.After(approvedAt[n.Author.Username]) {
+						approvedAt[n.Author.Username] = n.CreatedAt
+					}
+					continue
+				}
+				c := &Comment{
+					ID:        strconv.Itoa(n.ID),
+					Author:    n.Author.Username,
+					Body:      n.Body,
+					CreatedAt: n.CreatedAt,
+					InReplyTo: root,
+				}
+				// only notes left on the diff carry a position
+				if n.Position == nil {
+					overviewNotes = append(overviewNotes, c)
+					continue
+				}
+				c.Path = n.Position.NewPath
+				c.Line = n.Position.NewLine
+				if c.Line == 0 {
+					c.Line = n.Position.OldLine
+				}
+				diffNotes = append(diffNotes, c)
+				if len(root) == 0 {
+					root = c.ID
+					if n.Resolved {
+						pr.ResolvedThreads = append(pr.ResolvedThreads, root)
+					}
+				}
+			}
+		}
+		pr.Comments = mergeComments(pr.Comments, diffNotes)
+		pr.IssueComments = mergeComments(pr.IssueComments, overviewNotes)
+		if nextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(nextPage))
+	}
+
+	g.ds.writeStatus(fmt.Sprintf("%s/%s/!%d fetching approvals...", pr.OrgName, pr.RepoName, pr.Number))
+	approvals := &gitlabApprovals{}
+	if _, err := g.get(ctx, mrPath+"/approvals", url.Values{}, approvals); err != nil {
+		return err
+	}
+	// approvals are current state only so they replace what was there. The
+	// approved commit is not reported and without a note the time is not
+	// known either, which keeps the approval out of the activity timeline.
+	pr.Reviews = []*Review{}
+	for _, a := range approvals.ApprovedBy {
+		pr.Reviews = append(pr.Reviews, &Review{
+			ID:          fmt.Sprintf("%s/approval/%s", pr.ID, a.User.Username),
+			Author:      a.User.Username,
+			State:       "APPROVED",
+			SubmittedAt: approvedAt[a.User.Username],
+		})
+	}
+
+	// merge requests do not carry line counts so tally them from the diff
+	changes := &gitlabChanges{}
+	if _, err := g.get(ctx, mrPath+"/changes", url.Values{}, changes); err != nil {
+		return err
+	}
+	pr.Additions = 0
+	pr.Deletions = 0
+	pr.ChangedFiles = []string{}
+	for _, c := range changes.Changes {
+		pr.ChangedFiles = append(pr.ChangedFiles, c.NewPath)
+		additions, deletions := countDiffLines(c.Diff)
+		pr.Additions += additions
+		pr.Deletions += deletions
+	}
+	return nil
+}
+
+func (g *gitlabProvider) GetFileDiffs(ctx context.Context, pr *PullRequest) ([]*FileDiff, error) {
+	mrPath := fmt.Sprintf("%s/merge_requests/%d", projectPath(pr.OrgName, pr.RepoName), pr.Number)
+	changes := &gitlabChanges{}
+	if _, err := g.get(ctx, mrPath+"/changes", url.Values{}, changes); err != nil {
+		return nil, err
+	}
+	files := []*FileDiff{}
+	for _, c := range changes.Changes {
+		f := &FileDiff{
+			Path:   c.NewPath,
+			Status: "modified",
+			Patch:  c.Diff,
+		}
+		switch {
+		case c.NewFile:
+			f.Status = "added"
+		case c.DeletedFile:
+			f.Status = "removed"
+		case c.RenamedFile:
+			f.Status = "renamed"
+			f.PreviousPath = c.OldPath
+		}
+		f.Additions, f.Deletions = countDiffLines(c.Diff)
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Tallies added and removed lines in a unified diff
+func countDiffLines(diff string) (int, int) {
+	additions, deletions := 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			additions++
+		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+			deletions++
+		}
+	}
+	return additions, deletions
+}
+
+// Gitlab has no change request state so only approvals and comments are
+// supported. An approval's body is left as a comment alongside it.
+func (g *gitlabProvider) SubmitReview(ctx context.Context, pr *PullRequest, event string, body string) error {
+	mrPath := fmt.Sprintf("%s/merge_requests/%d", projectPath(pr.OrgName, pr.RepoName), pr.Number)
+	switch event {
+	case ReviewEventApprove:
+		if err := g.post(ctx, mrPath+"/approve", map[string]string{}); err != nil {
+			return err
+		}
+	case ReviewEventComment:
+	default:
+		return fmt.Errorf("gitlab merge requests do not support %s", event)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return g.post(ctx, mrPath+"/notes", map[string]string{"body": body})
+}
+
+// Approval rules are set per project, the branch is ignored
+func (g *gitlabProvider) GetRequiredApprovals(ctx context.Context, orgName string, repoName string, branch string) (int, error) {
+	approvals := &gitlabProjectApprovals{}
+	if _, err := g.get(ctx, projectPath(orgName, repoName)+"/approvals", url.Values{}, approvals); err != nil {
+		return 0, err
+	}
+	return approvals.ApprovalsBeforeMerge, nil
+}
+
+func (g *gitlabProvider) GetChecksState(ctx context.Context, pr *PullRequest) (string, error) {
+	mr := &gitlabMergeRequest{}
+	if _, err := g.get(ctx, fmt.Sprintf("%s/merge_requests/%d", projectPath(pr.OrgName, pr.RepoName), pr.Number), url.Values{}, mr); err != nil {
+		return "", err
+	}
+	if mr.HeadPipeline == nil {
+		return "", nil
+	}
+	return gitlabPipelineState(mr.HeadPipeline.Status), nil
+}
+
+func gitlabPipelineState(status string) string {
+	switch status {
+	case "success", "skipped", "manual":
+		return CheckStateSuccess
+	case "failed", "canceled":
+		return CheckStateFailure
+	default:
+		return CheckStatePending
+	}
+}
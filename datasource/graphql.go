@@ -0,0 +1,542 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/inburst/prty/logger"
+)
+
+// Pulls the most recently updated PRs for a repo along with the commits, reviews,
+// review threads, conversation comments and requested reviewers needed to score it.
+// One query returns a full page of hydrated PRs instead of 4+ REST calls per PR.
+// Lists ask for the newest items with last: so the head commit is always
+// included, a PR with more than that is hydrated through REST instead.
+const repoPullsQuery = `
+query($owner: String!, $repo: String!, $states: [PullRequestState!], $pageSize: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequests(states: $states, first: $pageSize, after: $after, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id
+        databaseId
+        number
+        title
+        body
+        url
+        state
+        headRefOid
+        baseRefName
+        mergeable
+        isDraft
+        additions
+        deletions
+        createdAt
+        updatedAt
+        author { login }
+        labels(first: 20) { nodes { name } }
+        reviewRequests(first: 20) {
+          nodes { requestedReviewer { ... on User { login } ... on Team { combinedSlug } } }
+        }
+        comments(last: 100) {
+          pageInfo { hasPreviousPage }
+          nodes {
+            databaseId
+            body
+            createdAt
+            author { login }
+          }
+        }
+        files(first: 100) {
+          pageInfo { hasNextPage }
+          nodes { path }
+        }
+        commits(last: 100) {
+          pageInfo { hasPreviousPage }
+          nodes { commit { oid committedDate statusCheckRollup { state } } }
+        }
+        reviews(last: 100) {
+          pageInfo { hasPreviousPage }
+          nodes {
+            id
+            databaseId
+            state
+            body
+            submittedAt
+            author { login }
+            commit { oid }
+          }
+        }
+        reviewThreads(first: 50) {
+          pageInfo { hasNextPage }
+          nodes {
+            isResolved
+            isOutdated
+            comments(first: 50) {
+              pageInfo { hasNextPage }
+              nodes {
+                databaseId
+                body
+                path
+                line
+                originalLine
+                diffHunk
+                createdAt
+                author { login }
+                replyTo { databaseId }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// Resolution is only tracked by graphql so the REST provider asks for it
+// separately, a page at a time. A thread is identified by its first comment.
+const pullThreadStatesQuery = `
+query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          isResolved
+          isOutdated
+          comments(first: 1) { nodes { databaseId } }
+        }
+      }
+    }
+  }
+}`
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// requestedReviewer is a union of User and Team
+type gqlReviewer struct {
+	Login        string `json:"login"`
+	CombinedSlug string `json:"combinedSlug"`
+}
+
+type gqlActor struct {
+	Login string `json:"login"`
+}
+
+// hasPreviousPage is only asked for on connections read with last:
+type gqlPageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	EndCursor       string `json:"endCursor"`
+}
+
+type gqlRepoPulls struct {
+	Repository struct {
+		PullRequests struct {
+			PageInfo gqlPageInfo      `json:"pageInfo"`
+			Nodes    []gqlPullRequest `json:"nodes"`
+		} `json:"pullRequests"`
+	} `json:"repository"`
+}
+
+type gqlPullRequest struct {
+	ID         string    `json:"id"`
+	DatabaseID int64     `json:"databaseId"`
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	URL        string    `json:"url"`
+	State      string    `json:"state"`
+	HeadRefOID string    `json:"headRefOid"`
+	BaseRef    string    `json:"baseRefName"`
+	Mergeable  string    `json:"mergeable"`
+	IsDraft    bool      `json:"isDraft"`
+	Additions  int       `json:"additions"`
+	Deletions  int       `json:"deletions"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Author     *gqlActor `json:"author"`
+	Labels     struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer *gqlReviewer `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	Comments struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			DatabaseID int64     `json:"databaseId"`
+			Body       string    `json:"body"`
+			CreatedAt  time.Time `json:"createdAt"`
+			Author     *gqlActor `json:"author"`
+		} `json:"nodes"`
+	} `json:"comments"`
+	Files struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Path string `json:"path"`
+		} `json:"nodes"`
+	} `json:"files"`
+	Commits struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Commit struct {
+				OID               string    `json:"oid"`
+				CommittedDate     time.Time `json:"committedDate"`
+				StatusCheckRollup *struct {
+					State string `json:"state"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+	Reviews struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []gqlReview `json:"nodes"`
+	} `json:"reviews"`
+	ReviewThreads gqlReviewThreads `json:"reviewThreads"`
+}
+
+type gqlReviewThreads struct {
+	PageInfo gqlPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		IsResolved bool `json:"isResolved"`
+		IsOutdated bool `json:"isOutdated"`
+		Comments   struct {
+			PageInfo gqlPageInfo `json:"pageInfo"`
+			Nodes    []struct {
+				DatabaseID   int64     `json:"databaseId"`
+				Body         string    `json:"body"`
+				Path         string    `json:"path"`
+				Line         *int      `json:"line"`
+				OriginalLine *int      `json:"originalLine"`
+				DiffHunk     string    `json:"diffHunk"`
+				CreatedAt    time.Time `json:"createdAt"`
+				Author       *gqlActor `json:"author"`
+				ReplyTo      *struct {
+					DatabaseID int64 `json:"databaseId"`
+				} `json:"replyTo"`
+			} `json:"nodes"`
+		} `json:"comments"`
+	} `json:"nodes"`
+}
+
+// Root comment ids of the resolved and outdated threads
+func (t *gqlReviewThreads) states() ([]string, []string) {
+	resolved := []string{}
+	outdated := []string{}
+	for _, thread := range t.Nodes {
+		if len(thread.Comments.Nodes) == 0 {
+			continue
+		}
+		id := strconv.FormatInt(thread.Comments.Nodes[0].DatabaseID, 10)
+		if thread.IsResolved {
+			resolved = append(resolved, id)
+		}
+		if thread.IsOutdated {
+			outdated = append(outdated, id)
+		}
+	}
+	return resolved, outdated
+}
+
+type gqlReview struct {
+	ID          string     `json:"id"`
+	DatabaseID  int64      `json:"databaseId"`
+	State       string     `json:"state"`
+	Body        string     `json:"body"`
+	SubmittedAt *time.Time `json:"submittedAt"`
+	Author      *gqlActor  `json:"author"`
+	Commit      *struct {
+		OID string `json:"oid"`
+	} `json:"commit"`
+}
+
+// github.com serves graphql at api.github.com/graphql while enterprise
+// servers expose it at /api/graphql next to the /api/v3/ REST root
+func graphQLEndpoint() string {
+	if strings.HasSuffix(sharedClient().BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
+
+func graphQLQuery(ctx context.Context, query string, variables map[string]interface{}, v interface{}) (*github.Response, error) {
+	req, err := sharedClient().NewRequest("POST", graphQLEndpoint(), &graphQLRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &graphQLResponse{}
+	resp, err := sharedClient().Do(ctx, req, result)
+	if err != nil {
+		return resp, err
+	}
+	if len(result.Errors) > 0 {
+		messages := []string{}
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return resp, errors.New(strings.Join(messages, "; "))
+	}
+	return resp, json.Unmarshal(result.Data, v)
+}
+
+// githubGraphQLProvider lists orgs and repos through REST like the github
+// provider but hydrates PRs a page at a time through the GraphQL v4 api
+type githubGraphQLProvider struct {
+	*githubProvider
+}
+
+func newGithubGraphQLProvider(ds *Datasource, username string) *githubGraphQLProvider {
+	return &githubGraphQLProvider{
+		githubProvider: newGithubProvider(ds, username),
+	}
+}
+
+func (g *githubGraphQLProvider) GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error) {
+	variables := map[string]interface{}{
+		"owner":    orgName,
+		"repo":     repoName,
+		"states":   []string{"OPEN"},
+		"pageSize": 10,
+		"after":    nil,
+	}
+	// closing a PR bumps updatedAt so it will show up before the cutoff
+	if !since.IsZero() {
+		variables["states"] = []string{"OPEN", "CLOSED", "MERGED"}
+	}
+
+	var allPulls []*PullRequest
+	for {
+		logger.Shared().Printf("graphql pulls for: [%s/%s] after:%v", orgName, repoName, variables["after"])
+		page := &gqlRepoPulls{}
+		_, err := g.ds.scheduler.do(ctx, func() (*github.Response, error) {
+			return graphQLQuery(ctx, repoPullsQuery, variables, page)
+		})
+		if err != nil {
+			logger.Shared().Printf("error getting graphql pulls in [%s/%s] %s", orgName, repoName, err)
+			return allPulls, err
+		}
+
+		pulls := page.Repository.PullRequests
+		reachedSince := false
+		for i := range pulls.Nodes {
+			pr := pulls.Nodes[i].toPullRequest()
+			pr.OrgName = orgName
+			pr.RepoName = repoName
+			if !since.IsZero() && !pr.UpdatedAt.After(since) {
+				reachedSince = true
+				break
+			}
+			allPulls = append(allPulls, pr)
+		}
+		if reachedSince || !pulls.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = pulls.PageInfo.EndCursor
+	}
+	return allPulls, nil
+}
+
+// Only reached for PRs too big for the listing query or when a single PR is
+// refreshed, e.g. after I review it
+func (g *githubGraphQLProvider) HydratePull(ctx context.Context, pr *PullRequest) error {
+	return g.githubProvider.HydratePull(ctx, pr)
+}
+
+// Anything that did not fit in one page is left to the REST hydrate, which
+// pages through all of it
+func (g *gqlPullRequest) truncated() bool {
+	if g.Commits.PageInfo.HasPreviousPage || g.Reviews.PageInfo.HasPreviousPage ||
+		g.Comments.PageInfo.HasPreviousPage || g.ReviewThreads.PageInfo.HasNextPage ||
+		g.Files.PageInfo.HasNextPage {
+		return true
+	}
+	for _, t := range g.ReviewThreads.Nodes {
+		if t.Comments.PageInfo.HasNextPage {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gqlPullRequest) toPullRequest() *PullRequest {
+	pr := &PullRequest{
+		Provider:   GithubProviderName,
+		ID:         g.ID,
+		Number:     g.Number,
+		Title:      g.Title,
+		Body:       g.Body,
+		URL:        g.URL,
+		HeadSHA:    g.HeadRefOID,
+		BaseBranch: g.BaseRef,
+		Author:     g.Author.login(),
+		IsDraft:    g.IsDraft,
+		Additions:  g.Additions,
+		Deletions:  g.Deletions,
+		CreatedAt:  g.CreatedAt,
+		UpdatedAt:  g.UpdatedAt,
+		IsClosed:   g.State != "OPEN",
+
+		HasConflicts: g.Mergeable == "CONFLICTING",
+
+		Labels:             []string{},
+		RequestedReviewers: []string{},
+		RequestedTeams:     []string{},
+		hydrated:           !g.truncated(),
+	}
+	for _, l := range g.Labels.Nodes {
+		pr.Labels = append(pr.Labels, l.Name)
+	}
+	for _, r := range g.ReviewRequests.Nodes {
+		if r.RequestedReviewer == nil {
+			continue
+		}
+		// users have a login, teams have an org/slug
+		if len(r.RequestedReviewer.Login) > 0 {
+			pr.RequestedReviewers = append(pr.RequestedReviewers, r.RequestedReviewer.Login)
+		} else if len(r.RequestedReviewer.CombinedSlug) > 0 {
+			pr.RequestedTeams = append(pr.RequestedTeams, r.RequestedReviewer.CombinedSlug)
+		}
+	}
+
+	pr.ChangedFiles = []string{}
+	for _, f := range g.Files.Nodes {
+		pr.ChangedFiles = append(pr.ChangedFiles, f.Path)
+	}
+
+	for _, c := range g.Comments.Nodes {
+		pr.IssueComments = append(pr.IssueComments, &Comment{
+			ID:        strconv.FormatInt(c.DatabaseID, 10),
+			Author:    c.Author.login(),
+			Body:      c.Body,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	for _, c := range g.Commits.Nodes {
+		// the rollup already combines statuses and check runs
+		if c.Commit.OID == g.HeadRefOID && c.Commit.StatusCheckRollup != nil {
+			switch c.Commit.StatusCheckRollup.State {
+			case "SUCCESS":
+				pr.ChecksState = CheckStateSuccess
+			case "PENDING", "EXPECTED":
+				pr.ChecksState = CheckStatePending
+			default:
+				pr.ChecksState = CheckStateFailure
+			}
+		}
+		pr.Commits = append(pr.Commits, &Commit{
+			ID:          c.Commit.OID,
+			SHA:         c.Commit.OID,
+			CommittedAt: c.Commit.CommittedDate,
+		})
+	}
+
+	for _, r := range g.Reviews.Nodes {
+		review := &Review{
+			ID:     strconv.FormatInt(r.DatabaseID, 10),
+			Author: r.Author.login(),
+			State:  r.State,
+			Body:   r.Body,
+		}
+		if r.SubmittedAt != nil {
+			review.SubmittedAt = *r.SubmittedAt
+		}
+		if r.Commit != nil {
+			review.CommitSHA = r.Commit.OID
+		}
+		pr.Reviews = append(pr.Reviews, review)
+	}
+
+	for _, t := range g.ReviewThreads.Nodes {
+		for _, c := range t.Comments.Nodes {
+			comment := &Comment{
+				ID:        strconv.FormatInt(c.DatabaseID, 10),
+				Author:    c.Author.login(),
+				Body:      c.Body,
+				Path:      c.Path,
+				DiffHunk:  c.DiffHunk,
+				CreatedAt: c.CreatedAt,
+			}
+			// outdated comments no longer have a line in the current diff
+			if c.Line != nil {
+				comment.Line = *c.Line
+			} else if c.OriginalLine != nil {
+				comment.Line = *c.OriginalLine
+			}
+			if c.ReplyTo != nil {
+				comment.InReplyTo = strconv.FormatInt(c.ReplyTo.DatabaseID, 10)
+			}
+			pr.Comments = append(pr.Comments, comment)
+		}
+	}
+	pr.ResolvedThreads, pr.OutdatedThreads = g.ReviewThreads.states()
+	return pr
+}
+
+func (g *githubProvider) GetThreadStatesForPull(ctx context.Context, org string, repo string, prNumber int) ([]string, []string, error) {
+	variables := map[string]interface{}{
+		"owner":  org,
+		"repo":   repo,
+		"number": prNumber,
+		"after":  nil,
+	}
+	resolved := []string{}
+	outdated := []string{}
+	for {
+		page := &struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads gqlReviewThreads `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}{}
+		_, err := g.ds.scheduler.do(ctx, func() (*github.Response, error) {
+			return graphQLQuery(ctx, pullThreadStatesQuery, variables, page)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		threads := page.Repository.PullRequest.ReviewThreads
+		pageResolved, pageOutdated := threads.states()
+		resolved = append(resolved, pageResolved...)
+		outdated = append(outdated, pageOutdated...)
+		if !threads.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = threads.PageInfo.EndCursor
+	}
+	return resolved, outdated, nil
+}
+
+// deleted accounts come back as a null author
+func (a *gqlActor) login() string {
+	if a == nil {
+		return "ghost"
+	}
+	return a.Login
+}
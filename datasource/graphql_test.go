@@ -0,0 +1,196 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+)
+
+var _ = Describe("toPullRequest", func() {
+	parse := func(data string) *PullRequest {
+		g := &gqlPullRequest{}
+		Expect(json.Unmarshal([]byte(data), g)).To(Succeed())
+		return g.toPullRequest()
+	}
+
+	It("should take the checks from the head commit", func() {
+		pr := parse(`{
+			"headRefOid": "head",
+			"commits": {
+				"pageInfo": {"hasPreviousPage": false},
+				"nodes": [
+					{"commit": {"oid": "old", "statusCheckRollup": {"state": "FAILURE"}}},
+					{"commit": {"oid": "head", "statusCheckRollup": {"state": "SUCCESS"}}}
+				]
+			}
+		}`)
+		Expect(pr.ChecksState).To(Equal(CheckStateSuccess))
+		Expect(pr.Commits).To(HaveLen(2))
+		Expect(pr.hydrated).To(BeTrue())
+	})
+
+	It("should leave PRs with more commits than fit in a page to be hydrated", func() {
+		pr := parse(`{
+			"headRefOid": "head",
+			"commits": {
+				"pageInfo": {"hasPreviousPage": true},
+				"nodes": [{"commit": {"oid": "head"}}]
+			}
+		}`)
+		Expect(pr.hydrated).To(BeFalse())
+	})
+
+	It("should leave PRs with more reviews than fit in a page to be hydrated", func() {
+		pr := parse(`{
+			"reviews": {
+				"pageInfo": {"hasPreviousPage": true},
+				"nodes": [{"databaseId": 1, "state": "APPROVED"}]
+			}
+		}`)
+		Expect(pr.hydrated).To(BeFalse())
+	})
+
+	It("should leave PRs with more comments than fit in a page to be hydrated", func() {
+		pr := parse(`{
+			"comments": {
+				"pageInfo": {"hasPreviousPage": true},
+				"nodes": [{"databaseId": 1, "body": "newest"}]
+			}
+		}`)
+		Expect(pr.IssueComments).To(HaveLen(1))
+		Expect(pr.hydrated).To(BeFalse())
+	})
+
+	It("should leave PRs with more review threads than fit in a page to be hydrated", func() {
+		pr := parse(`{
+			"reviewThreads": {
+				"pageInfo": {"hasNextPage": true},
+				"nodes": [{"comments": {"nodes": [{"databaseId": 1}]}}]
+			}
+		}`)
+		Expect(pr.Comments).To(HaveLen(1))
+		Expect(pr.hydrated).To(BeFalse())
+	})
+
+	It("should leave PRs with a thread too long to fit in a page to be hydrated", func() {
+		pr := parse(`{
+			"reviewThreads": {
+				"pageInfo": {"hasNextPage": false},
+				"nodes": [{"comments": {"pageInfo": {"hasNextPage": true}, "nodes": [{"databaseId": 1}]}}]
+			}
+		}`)
+		Expect(pr.hydrated).To(BeFalse())
+	})
+})
+
+// a PR changing 101 files, the listing query only has room for 100
+var manyFiles = func() []string {
+	paths := []string{}
+	for i := 0; i < 101; i++ {
+		paths = append(paths, fmt.Sprintf("pkg/file%03d.go", i))
+	}
+	return paths
+}()
+
+var _ = Describe("files of a PR with more than a page of them", func() {
+	It("should leave the PR to be hydrated", func() {
+		nodes := []string{}
+		for _, path := range manyFiles[:100] {
+			nodes = append(nodes, fmt.Sprintf(`{"path": %q}`, path))
+		}
+		g := &gqlPullRequest{}
+		Expect(json.Unmarshal([]byte(`{"files": {
+			"pageInfo": {"hasNextPage": true},
+			"nodes": [`+strings.Join(nodes, ",")+`]
+		}}`), g)).To(Succeed())
+
+		pr := g.toPullRequest()
+		Expect(pr.ChangedFiles).To(HaveLen(100))
+		Expect(pr.hydrated).To(BeFalse())
+	})
+
+	It("should list every file when hydrating through REST", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			files := manyFiles[:100]
+			if r.URL.Query().Get("page") == "2" {
+				files = manyFiles[100:]
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+			}
+			nodes := []string{}
+			for _, path := range files {
+				nodes = append(nodes, fmt.Sprintf(`{"filename": %q}`, path))
+			}
+			w.Write([]byte("[" + strings.Join(nodes, ",") + "]"))
+		}))
+		defer server.Close()
+
+		previous := sharedGithubClient
+		defer func() { sharedGithubClient = previous }()
+		sharedGithubClient = github.NewClient(nil)
+		sharedGithubClient.BaseURL, _ = url.Parse(server.URL + "/")
+
+		provider := newGithubProvider(New(&config.Config{GithubUsername: "me"}), "me")
+		files, err := provider.GetAllFilesForPull(context.Background(), "org", "repo", 7)
+		Expect(err).NotTo(HaveOccurred())
+		paths := []string{}
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		Expect(paths).To(Equal(manyFiles))
+	})
+})
+
+var _ = Describe("GetThreadStatesForPull", func() {
+	var (
+		server   *httptest.Server
+		previous *github.Client
+		// the cursor each query asked to start after
+		afters []interface{}
+	)
+
+	BeforeEach(func() {
+		afters = []interface{}{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request := &graphQLRequest{}
+			json.NewDecoder(r.Body).Decode(request)
+			afters = append(afters, request.Variables["after"])
+
+			page := `{"pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+				"nodes": [{"isResolved": true, "comments": {"nodes": [{"databaseId": 1}]}}]}`
+			if request.Variables["after"] == "c1" {
+				page = `{"pageInfo": {"hasNextPage": false},
+					"nodes": [{"isResolved": true, "isOutdated": true, "comments": {"nodes": [{"databaseId": 2}]}}]}`
+			}
+			w.Write([]byte(`{"data": {"repository": {"pullRequest": {"reviewThreads": ` + page + `}}}}`))
+		}))
+
+		previous = sharedGithubClient
+		sharedGithubClient = github.NewClient(nil)
+		sharedGithubClient.BaseURL, _ = url.Parse(server.URL + "/")
+	})
+
+	AfterEach(func() {
+		sharedGithubClient = previous
+		server.Close()
+	})
+
+	It("should page through every thread", func() {
+		provider := newGithubProvider(New(&config.Config{GithubUsername: "me"}), "me")
+		resolved, outdated, err := provider.GetThreadStatesForPull(context.Background(), "org", "repo", 7)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal([]string{"1", "2"}))
+		Expect(outdated).To(Equal([]string{"2"}))
+		Expect(afters).To(Equal([]interface{}{nil, "c1"}))
+	})
+})
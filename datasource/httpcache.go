@@ -0,0 +1,223 @@
+package datasource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inburst/prty/logger"
+)
+
+// etagTransport persists the validators and body of every successful GET so
+// repeat requests can be made conditional. Github answers unchanged
+// resources with a 304 which does not count against the rate limit, the
+// cached body is then served from disk.
+type etagTransport struct {
+	base http.RoundTripper
+	dir  string
+	// the least recently used entries are dropped past this many
+	maxEntries int
+
+	mutex   sync.Mutex
+	entries int
+}
+
+// Every PR's commits, comments and reviews pages add up, this keeps the
+// cache to tens of megabytes
+const defaultHTTPCacheEntries = 5000
+
+type cachedResponse struct {
+	ETag         string      `json:"ETag"`
+	LastModified string      `json:"LastModified"`
+	StatusCode   int         `json:"StatusCode"`
+	Header       http.Header `json:"Header"`
+	Body         []byte      `json:"Body"`
+}
+
+func newETagTransport(base http.RoundTripper, dir string, maxEntries int) *etagTransport {
+	os.MkdirAll(dir, 0755)
+	t := &etagTransport{
+		base:       base,
+		dir:        dir,
+		maxEntries: maxEntries,
+	}
+	t.entries = len(t.listEntries())
+	return t
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := t.cacheKey(req)
+	cached := t.load(key)
+	if cached != nil {
+		// RoundTrippers must not modify the request they were given
+		req = req.Clone(req.Context())
+		if len(cached.ETag) > 0 {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if len(cached.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return cached.toResponse(req, resp.Header), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (len(etag) == 0 && len(lastModified) == 0) {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.save(key, &cachedResponse{
+		ETag:         etag,
+		LastModified: lastModified,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+	})
+	return resp, nil
+}
+
+// The token is part of the key so switching accounts never serves data the
+// new token can not see
+func (t *etagTransport) cacheKey(req *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte(req.Header.Get("Accept")))
+	h.Write([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *etagTransport) load(key string) *cachedResponse {
+	path := filepath.Join(t.dir, key+".json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	cached := &cachedResponse{}
+	if err = json.Unmarshal(data, cached); err != nil {
+		return nil
+	}
+	// the modified time doubles as the last use for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return cached
+}
+
+func (t *etagTransport) save(key string, cached *cachedResponse) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		logger.Shared().Printf("http cache marshal err %s\n", err)
+		return
+	}
+	// write then rename so concurrent readers never see a partial file
+	path := filepath.Join(t.dir, key+".json")
+	tmp, err := ioutil.TempFile(t.dir, key)
+	if err != nil {
+		logger.Shared().Printf("http cache write err %s\n", err)
+		return
+	}
+	tmp.Write(data)
+	tmp.Close()
+	_, statErr := os.Stat(path)
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		logger.Shared().Printf("http cache rename err %s\n", err)
+		return
+	}
+	if os.IsNotExist(statErr) {
+		t.added()
+	}
+}
+
+// Counts a new entry and evicts down to 90% of the cap once it is passed so
+// the directory is not listed on every save
+func (t *etagTransport) added() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.entries++
+	if t.maxEntries <= 0 || t.entries <= t.maxEntries {
+		return
+	}
+
+	entries := t.listEntries()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+	keep := t.maxEntries - t.maxEntries/10
+	for len(entries) > keep {
+		os.Remove(filepath.Join(t.dir, entries[0].Name()))
+		entries = entries[1:]
+	}
+	t.entries = len(entries)
+}
+
+// In flight temp files have no .json suffix so they are left alone
+func (t *etagTransport) listEntries() []os.FileInfo {
+	infos, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		return nil
+	}
+	entries := []os.FileInfo{}
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), ".json") {
+			entries = append(entries, info)
+		}
+	}
+	return entries
+}
+
+// Rebuilds the original response from disk. Rate limit headers are taken
+// from the live 304 so the remaining count stays accurate.
+func (c *cachedResponse) toResponse(req *http.Request, liveHeader http.Header) *http.Response {
+	header := http.Header{}
+	for k, v := range c.Header {
+		header[k] = v
+	}
+	for k, v := range liveHeader {
+		if strings.HasPrefix(k, "X-Ratelimit-") {
+			header[k] = v
+		}
+	}
+	header.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        http.StatusText(c.StatusCode),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,131 @@
+package datasource
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("etagTransport", func() {
+	var (
+		dir       string
+		server    *httptest.Server
+		client    *http.Client
+		transport *etagTransport
+		// If-None-Match of every request the server saw
+		conditions []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "httpcache")
+		Expect(err).NotTo(HaveOccurred())
+
+		conditions = []string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conditions = append(conditions, r.Header.Get("If-None-Match"))
+			etag := `"` + r.URL.Path + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write([]byte("body of " + r.URL.Path))
+		}))
+
+		transport = newETagTransport(http.DefaultTransport, dir, 2)
+		client = &http.Client{Transport: transport}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	get := func(path string, header http.Header) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	body := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	entries := func() []string {
+		names := []string{}
+		for _, info := range transport.listEntries() {
+			names = append(names, info.Name())
+		}
+		return names
+	}
+
+	It("should serve a 304 from the cached body", func() {
+		Expect(body(get("/a", nil))).To(Equal("body of /a"))
+
+		resp := get("/a", nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("X-From-Cache")).To(Equal("1"))
+		Expect(body(resp)).To(Equal("body of /a"))
+		Expect(conditions).To(Equal([]string{"", `"/a"`}))
+	})
+
+	It("should keep responses for different tokens apart", func() {
+		get("/a", http.Header{"Authorization": {"token one"}}).Body.Close()
+		get("/a", http.Header{"Authorization": {"token two"}}).Body.Close()
+		Expect(conditions).To(Equal([]string{"", ""}))
+	})
+
+	It("should keep responses for different media types apart", func() {
+		get("/a", http.Header{"Accept": {"application/json"}}).Body.Close()
+		get("/a", http.Header{"Accept": {"application/vnd.github.diff"}}).Body.Close()
+		Expect(conditions).To(Equal([]string{"", ""}))
+	})
+
+	It("should not cache other methods", func() {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Post(server.URL+"/a", "application/json", strings.NewReader("{}"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Header.Get("X-From-Cache")).To(BeEmpty())
+			resp.Body.Close()
+		}
+		Expect(conditions).To(Equal([]string{"", ""}))
+		Expect(entries()).To(BeEmpty())
+	})
+
+	It("should evict the least recently used entry past the cap", func() {
+		get("/a", nil).Body.Close()
+		get("/b", nil).Body.Close()
+		Expect(entries()).To(HaveLen(2))
+
+		// age both entries then use /a so /b is the oldest
+		past := time.Now().Add(-time.Hour)
+		for _, name := range entries() {
+			Expect(os.Chtimes(filepath.Join(dir, name), past, past)).To(Succeed())
+		}
+		get("/a", nil).Body.Close()
+
+		get("/c", nil).Body.Close()
+		Expect(entries()).To(HaveLen(2))
+
+		conditions = []string{}
+		get("/a", nil).Body.Close()
+		get("/b", nil).Body.Close()
+		Expect(conditions).To(Equal([]string{`"/a"`, ""}))
+	})
+})
@@ -34,7 +34,7 @@ func GithubGet() {
 				continue
 			}
 			if len(orgWhitelist) == 0 || listContains(orgWhitelist, orgName) {
-				repos, _ := GetAllReposForOrg(orgName)
+				repos, _, _ := client.Repositories.ListByOrg(ctx, orgName, nil)
 				for i, r := range repos {
 					fmt.Printf("%d %s -- %s\n", i, orgName, *r.Name)
 					/*
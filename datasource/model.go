@@ -0,0 +1,140 @@
+package datasource
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/inburst/prty/config"
+)
+
+const ModelVersion = 1
+
+// Inputs to the learned importance model. These are the fields recorded in
+// the training data so every row can be turned back into a feature vector.
+type ModelFeatures struct {
+	IAmAuthor                  bool
+	AuthorIsTeammate           bool
+	AuthorIsBot                bool
+	HasChangesAfterLastComment bool
+	HasCommentsFromMe          bool
+	LastCommentFromMe          bool
+	IsDraft                    bool
+	CodeDelta                  int
+	TimeSinceLastComment       time.Duration
+	TimeSinceLastCommit        time.Duration
+	TimeSinceFirstCommit       time.Duration
+}
+
+// Names of the entries in ModelFeatures.Vector, in order
+var ModelFeatureNames = []string{
+	"Not mine",
+	"Teammate",
+	"Bot",
+	"Changes after comment",
+	"Commented",
+	"Last comment mine",
+	"Draft",
+	"Code delta",
+	"Since comment",
+	"Since commit",
+	"Age",
+}
+
+// Counts and durations are log scaled so a handful of huge PRs or ancient
+// timestamps do not dominate the fit
+func (f ModelFeatures) Vector() []float64 {
+	return []float64{
+		boolFeature(!f.IAmAuthor),
+		boolFeature(f.AuthorIsTeammate),
+		boolFeature(f.AuthorIsBot),
+		boolFeature(f.HasChangesAfterLastComment),
+		boolFeature(f.HasCommentsFromMe),
+		boolFeature(f.LastCommentFromMe),
+		boolFeature(f.IsDraft),
+		math.Log1p(math.Max(float64(f.CodeDelta), 0)),
+		math.Log1p(math.Max(f.TimeSinceLastComment.Hours(), 0)),
+		math.Log1p(math.Max(f.TimeSinceLastCommit.Hours(), 0)),
+		math.Log1p(math.Max(f.TimeSinceFirstCommit.Hours()/24, 0)),
+	}
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (pr *PullRequest) modelFeatures() ModelFeatures {
+	return ModelFeatures{
+		IAmAuthor:                  pr.IAmAuthor,
+		AuthorIsTeammate:           pr.AuthorIsTeammate,
+		AuthorIsBot:                pr.AuthorIsBot,
+		HasChangesAfterLastComment: pr.HasChangesAfterLastComment,
+		HasCommentsFromMe:          pr.HasCommentsFromMe,
+		LastCommentFromMe:          pr.LastCommentFromMe,
+		IsDraft:                    pr.IsDraft,
+		CodeDelta:                  pr.CodeDelta,
+		TimeSinceLastComment:       pr.TimeSinceLastComment,
+		TimeSinceLastCommit:        pr.TimeSinceLastCommit,
+		TimeSinceFirstCommit:       pr.TimeSinceFirstCommit,
+	}
+}
+
+// Model is a logistic regression predicting whether I will open a PR
+type Model struct {
+	Version   int       `json:"Version"`
+	TrainedAt time.Time `json:"TrainedAt"`
+	Samples   int       `json:"Samples"`
+
+	Features []string  `json:"Features"`
+	Weights  []float64 `json:"Weights"`
+	Bias     float64   `json:"Bias"`
+}
+
+// Log odds of opening the PR along with each feature's share of it
+func (m *Model) Score(f ModelFeatures) (float64, []float64) {
+	x := f.Vector()
+	contributions := make([]float64, len(x))
+	z := m.Bias
+	for i := range x {
+		contributions[i] = m.Weights[i] * x[i]
+		z += contributions[i]
+	}
+	return z, contributions
+}
+
+func LoadModel() (*Model, error) {
+	modelPath, err := config.GetModelFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	m := &Model{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	// a model trained on a different feature set can not be applied
+	if m.Version != ModelVersion || len(m.Weights) != len(ModelFeatureNames) {
+		return nil, errors.New("model was trained with a different version of prty, run `prty train` again")
+	}
+	return m, nil
+}
+
+func (m *Model) SaveToFile() error {
+	modelPath, err := config.GetModelFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(modelPath, data, 0644)
+}
@@ -7,13 +7,16 @@ import (
 	"github.com/inburst/prty/logger"
 )
 
-func GetAllOrgs() ([]*github.Organization, error) {
-	ctx := context.Background()
+func (g *githubProvider) listOrgs(ctx context.Context) ([]*github.Organization, error) {
 	opt := &github.ListOptions{PerPage: 10}
 	// get all pages of results
 	var allOrgs []*github.Organization
 	for {
-		orgs, resp, err := sharedClient().Organizations.List(ctx, "", opt)
+		var orgs []*github.Organization
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			orgs, resp, err = sharedClient().Organizations.List(ctx, "", opt)
+			return
+		})
 		if err != nil {
 			logger.Shared().Printf("org err: %s\n", err)
 			return allOrgs, err
@@ -30,10 +33,12 @@ func GetAllOrgs() ([]*github.Organization, error) {
 	return allOrgs, nil
 }
 
-func GetOrg(orgName string) (*github.Organization, error) {
-	ctx := context.Background()
-
-	org, _, err := sharedClient().Organizations.Get(ctx, orgName)
+func (g *githubProvider) getOrg(ctx context.Context, orgName string) (*github.Organization, error) {
+	var org *github.Organization
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		org, resp, err = sharedClient().Organizations.Get(ctx, orgName)
+		return
+	})
 	if err != nil {
 		logger.Shared().Printf("public org err: %s\n", err)
 		return nil, err
@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inburst/prty/logger"
+)
+
+// CODEOWNERS rarely changes so it is only refetched this often
+const codeOwnersTTL = time.Hour
+
+type repoCodeOwners struct {
+	owners    *CodeOwners
+	fetchedAt time.Time
+}
+
+// Replaces my team memberships. On failure the previous teams are kept.
+func (ds *Datasource) refreshMyTeams(ctx context.Context, tp teamsProvider) {
+	teams, err := tp.GetMyTeams(ctx)
+	if err != nil {
+		logger.Shared().Printf("error getting teams %s\n", err)
+		return
+	}
+	myTeams := map[string]bool{}
+	for _, t := range teams {
+		myTeams[strings.ToLower(t)] = true
+	}
+	ds.mutex.Lock()
+	ds.myTeams = myTeams
+	ds.mutex.Unlock()
+}
+
+func (ds *Datasource) refreshCodeOwners(ctx context.Context, cp codeOwnersProvider, repoKey string, orgName string, repoName string) {
+	ds.mutex.RLock()
+	existing, ok := ds.codeOwners[repoKey]
+	ds.mutex.RUnlock()
+	if ok && time.Since(existing.fetchedAt) < codeOwnersTTL {
+		return
+	}
+
+	ds.writeStatus(fmt.Sprintf("%s/%s fetching codeowners...", orgName, repoName))
+	owners, err := cp.GetCodeOwners(ctx, orgName, repoName)
+	if err != nil {
+		logger.Shared().Printf("error getting codeowners for [%s/%s] %s\n", orgName, repoName, err)
+		return
+	}
+	// a repo without the file has no owners rather than unknown owners
+	if owners == nil {
+		owners = &CodeOwners{}
+	}
+	ds.mutex.Lock()
+	ds.codeOwners[repoKey] = &repoCodeOwners{owners: owners, fetchedAt: time.Now()}
+	ds.mutex.Unlock()
+}
+
+// The CODEOWNERS rules for the PR's repo, false until they have been fetched
+func (ds *Datasource) codeOwnersFor(pr *PullRequest) (*CodeOwners, bool) {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	owners, ok := ds.codeOwners[fmt.Sprintf("%s/%s/%s", pr.Provider, pr.OrgName, pr.RepoName)]
+	if !ok {
+		return nil, false
+	}
+	return owners.owners, true
+}
+
+// Owners are written as @username, @org/team-slug or an email address
+func (ds *Datasource) isMeOrMyTeam(me string, owner string) bool {
+	owner = strings.ToLower(strings.TrimPrefix(owner, "@"))
+	if owner == strings.ToLower(me) {
+		return true
+	}
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	return ds.myTeams[owner]
+}
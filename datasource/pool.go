@@ -0,0 +1,51 @@
+package datasource
+
+import (
+	"context"
+	"sync"
+)
+
+const DefaultRefreshWorkers = 4
+
+// workerPool bounds how many fetch jobs run at once. Submit never blocks so
+// jobs are free to submit more jobs, they just wait for a free worker slot.
+// Jobs that have not started when the context is cancelled are dropped.
+type workerPool struct {
+	ctx   context.Context
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newWorkerPool(ctx context.Context, size int) *workerPool {
+	if size <= 0 {
+		size = DefaultRefreshWorkers
+	}
+	return &workerPool{
+		ctx:   ctx,
+		slots: make(chan struct{}, size),
+	}
+}
+
+func (p *workerPool) Submit(job func(ctx context.Context)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		select {
+		case p.slots <- struct{}{}:
+		case <-p.ctx.Done():
+			return
+		}
+		defer func() { <-p.slots }()
+
+		if p.ctx.Err() != nil {
+			return
+		}
+		job(p.ctx)
+	}()
+}
+
+// Blocks until every submitted job, including ones submitted by other
+// jobs, has finished or been dropped
+func (p *workerPool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,202 @@
+package datasource
+
+import (
+	"context"
+	"time"
+)
+
+const GithubProviderName = "github"
+const GitlabProviderName = "gitlab"
+
+// Provider is a code host prty can pull change requests from. Each
+// implementation maps its own API onto the provider neutral PullRequest
+// envelope so status, importance and the UI never need to know where a
+// PR came from.
+type Provider interface {
+	Name() string
+	// username of the authenticated user on this host
+	Username() string
+
+	GetAllOrgs(ctx context.Context) ([]string, error)
+	GetAllReposForOrg(ctx context.Context, orgName string) ([]string, error)
+	// Lists change requests updated after since, most recently updated first.
+	// A zero since lists every open change request. Otherwise closed and
+	// merged ones are included with IsClosed set so they can be dropped.
+	// The returned envelopes only need the metadata fields populated,
+	// HydratePull fills in the rest.
+	GetPullsForRepo(ctx context.Context, orgName string, repoName string, since time.Time) ([]*PullRequest, error)
+	// Fetches commits, inline and conversation comments, reviews, changed
+	// files and the check and mergeability state for the PR. Implementations should use
+	// the Last*Page fields to only fetch what is new since the last call.
+	HydratePull(ctx context.Context, pr *PullRequest) error
+}
+
+// Optional features a Provider can implement. The datasource checks for
+// them with a type assertion and skips the feature when missing.
+
+// codeOwnersProvider fetches a repo's CODEOWNERS file. Returns nil when
+// the repo does not have one.
+type codeOwnersProvider interface {
+	GetCodeOwners(ctx context.Context, orgName string, repoName string) (*CodeOwners, error)
+}
+
+// teamsProvider lists the teams I am a member of as "org/team-slug"
+type teamsProvider interface {
+	GetMyTeams(ctx context.Context) ([]string, error)
+}
+
+// reviewProvider posts a review on my behalf. event is one of the
+// ReviewEvent* values, comments are left on the conversation rather than a
+// line of the diff.
+type reviewProvider interface {
+	SubmitReview(ctx context.Context, pr *PullRequest, event string, body string) error
+}
+
+// diffProvider fetches the unified diff of every file changed by a PR
+type diffProvider interface {
+	GetFileDiffs(ctx context.Context, pr *PullRequest) ([]*FileDiff, error)
+}
+
+// checksProvider reads the CI state of a PR's head commit. CI finishing
+// does not bump a PR's updated time so this is asked again for PRs that are
+// otherwise unchanged.
+type checksProvider interface {
+	GetChecksState(ctx context.Context, pr *PullRequest) (string, error)
+}
+
+// approvalRulesProvider reads how many approving reviews a branch needs
+// before it can merge. Returns 0 when the branch is not protected.
+type approvalRulesProvider interface {
+	GetRequiredApprovals(ctx context.Context, orgName string, repoName string, branch string) (int, error)
+}
+
+// FileDiff is one changed file. Patch is the unified diff hunks and is empty
+// for binary files or diffs too large for the host to return.
+type FileDiff struct {
+	Path         string
+	PreviousPath string
+	Status       string // added, modified, removed or renamed
+	Additions    int
+	Deletions    int
+	Patch        string
+}
+
+type Commit struct {
+	ID          string
+	SHA         string
+	Author      string
+	CommittedAt time.Time
+}
+
+type Comment struct {
+	ID        string
+	Author    string
+	Body      string
+	Path      string
+	CreatedAt time.Time
+
+	// review comments only. Replies point at the first comment of their
+	// thread and DiffHunk is the diff the thread was started on.
+	InReplyTo string
+	Line      int
+	DiffHunk  string
+}
+
+// Review states use the github vocabulary
+// APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED
+type Review struct {
+	ID          string
+	Author      string
+	State       string
+	Body        string
+	CommitSHA   string
+	SubmittedAt time.Time
+}
+
+// Combined CI state of the head commit. An empty state means the commit has
+// no checks at all.
+const CheckStateSuccess = "success"
+const CheckStatePending = "pending"
+const CheckStateFailure = "failure"
+
+// Reduces individual check results to a single state. Any failure wins over
+// pending and pending wins over success.
+func combineCheckStates(states ...string) string {
+	combined := ""
+	for _, s := range states {
+		switch s {
+		case CheckStateFailure:
+			return CheckStateFailure
+		case CheckStatePending:
+			combined = CheckStatePending
+		case CheckStateSuccess:
+			if len(combined) == 0 {
+				combined = CheckStateSuccess
+			}
+		}
+	}
+	return combined
+}
+
+// Actions I can take on a PR. The values match github's review events.
+const ReviewEventApprove = "APPROVE"
+const ReviewEventRequestChanges = "REQUEST_CHANGES"
+const ReviewEventComment = "COMMENT"
+
+const ActivityReviewComment = "review_comment"
+const ActivityIssueComment = "comment"
+const ActivityReview = "review"
+
+// Activity is a single entry in the conversation on a PR
+type Activity struct {
+	Kind      string
+	Author    string
+	Body      string
+	State     string // only set for reviews
+	CreatedAt time.Time
+}
+
+func mergeCommits(existing []*Commit, fetched []*Commit) []*Commit {
+	seen := map[string]bool{}
+	for _, c := range existing {
+		seen[c.ID] = true
+	}
+	for _, c := range fetched {
+		if !seen[c.ID] {
+			existing = append(existing, c)
+			seen[c.ID] = true
+		}
+	}
+	return existing
+}
+
+// Refetched comments replace the cached copy so edits are picked up
+func mergeComments(existing []*Comment, fetched []*Comment) []*Comment {
+	seen := map[string]int{}
+	for i, c := range existing {
+		seen[c.ID] = i
+	}
+	for _, c := range fetched {
+		if i, ok := seen[c.ID]; ok {
+			existing[i] = c
+			continue
+		}
+		existing = append(existing, c)
+		seen[c.ID] = len(existing) - 1
+	}
+	return existing
+}
+
+func mergeReviews(existing []*Review, fetched []*Review) []*Review {
+	seen := map[string]bool{}
+	for _, r := range existing {
+		seen[r.ID] = true
+	}
+	for _, r := range fetched {
+		if !seen[r.ID] {
+			existing = append(existing, r)
+			seen[r.ID] = true
+		}
+	}
+	return existing
+}
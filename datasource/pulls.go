@@ -1,30 +1,67 @@
 package datasource
 
 import (
-	"context"
-	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/google/go-github/v53/github"
+	"github.com/inburst/prty/config"
 	"github.com/inburst/prty/logger"
 )
 
 type PullRequest struct {
-	PR               *github.PullRequest
-	Commits          []*github.RepositoryCommit
-	Comments         []*github.PullRequestComment
-	Reviews          []*github.PullRequestReview
-	LastCommitsPage  int
-	LastCommentsPage int
-	LastReviewsPage  int
+	Provider string
+	ID       string
+	Number   int
+	Title    string
+	Body     string
+	URL      string
+	HeadSHA  string
+	// branch the PR merges into
+	BaseBranch string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// closed or merged since the last refresh
+	IsClosed bool
+
+	Commits               []*Commit
+	Comments              []*Comment
+	IssueComments         []*Comment
+	Reviews               []*Review
+	LastCommitsPage       int
+	LastCommentsPage      int
+	LastIssueCommentsPage int
+	LastReviewsPage       int
+
+	// every comment and submitted review oldest first. Derived from the
+	// lists above on each status calculation.
+	Activity []*Activity `json:"-"`
+
+	// ids of the first comment of each resolved or outdated review thread,
+	// replaced in full on every hydrate
+	ResolvedThreads []string
+	OutdatedThreads []string
+	// review comments grouped into threads, derived like Activity
+	Threads []*Thread `json:"-"`
+	// unresolved threads I started or replied to, or on my PR, where
+	// someone else has the last word
+	UnansweredThreads int
+
+	ChangedFiles []string
+
+	// one of the CheckState* values, set by the provider for HeadSHA
+	ChecksState  string
+	HasConflicts bool
 
 	Author             string
 	RepoName           string
 	OrgName            string
 	Labels             []string
 	RequestedReviewers []string
-	NumCommits         int
+	// "org/team-slug" of every team asked to review
+	RequestedTeams []string
+	NumCommits     int
 
 	FirstCommitTime time.Time
 	LastCommitTime  time.Time
@@ -33,37 +70,87 @@ type PullRequest struct {
 	IAmAuthor                  bool
 	AuthorIsTeammate           bool
 	AuthorIsBot                bool
+	IAmOwner                   bool
+	IAmRequested               bool
+	IAmRequestedViaTeam        bool
 	HasChangesAfterLastComment bool
 	HasCommentsFromMe          bool
 	LastCommentFromMe          bool
 	TimeSinceLastComment       time.Duration
 	TimeSinceLastCommit        time.Duration
 	TimeSinceFirstCommit       time.Duration
-	IsApproved                 bool
-	IsAbandoned                bool
-	IsDraft                    bool
-	Additions                  int
-	Deletions                  int
-	CodeDelta                  int
+	TimeSinceLastActivity      time.Duration
+	// the same as above but only counting configured working hours
+	BusinessTimeSinceLastComment time.Duration
+	BusinessTimeSinceLastCommit  time.Duration
+	// enough current approvals for the base branch and no outstanding
+	// change requests
+	IsApproved bool
+	// approvals from each reviewer's latest review on the head commit
+	ApprovalCount      int
+	StaleApprovalCount int
+	RequiredApprovals  int
+	IsAbandoned        bool
+	IsDraft            bool
+	ChecksPassing      bool
+	ChecksPending      bool
+	ChecksFailing      bool
+	// one of the Turn* values
+	Turn      string
+	Additions int
+	Deletions int
+	CodeDelta int
 
 	Importance       float64
 	ImportanceLookup map[string]float64
 
 	ViewedAt *time.Time
+
+	// set when the provider returned commits, comments and reviews along
+	// with the listing so there is nothing left to hydrate
+	hydrated bool
+}
+
+// Carries local state and previously fetched pages over from the cached copy
+// of this PR so providers only need to fetch what is new. The lists are
+// copied since fetched pages are merged into them while the cached PR may
+// still be on screen.
+func (pr *PullRequest) mergeCached(cached *PullRequest) {
+	pr.ViewedAt = cached.ViewedAt
+	if pr.hydrated {
+		return
+	}
+	pr.copyLists(cached)
+	pr.LastCommitsPage = cached.LastCommitsPage
+	pr.LastCommentsPage = cached.LastCommentsPage
+	pr.LastIssueCommentsPage = cached.LastIssueCommentsPage
+	pr.LastReviewsPage = cached.LastReviewsPage
+	pr.Additions = cached.Additions
+	pr.Deletions = cached.Deletions
+}
+
+func (pr *PullRequest) copyLists(from *PullRequest) {
+	pr.Commits = append([]*Commit{}, from.Commits...)
+	pr.Comments = append([]*Comment{}, from.Comments...)
+	pr.IssueComments = append([]*Comment{}, from.IssueComments...)
+	pr.Reviews = append([]*Review{}, from.Reviews...)
 }
 
-func (pr *PullRequest) calculateStatusFields(orgName string, repoName string, ds *Datasource) {
-	pr.Author = pr.PR.User.GetLogin()
-	pr.OrgName = orgName
-	pr.RepoName = repoName
+func (pr *PullRequest) calculateStatusFields(ds *Datasource) {
+	me := ds.usernameFor(pr.Provider)
 	pr.NumCommits = len(pr.Commits)
-	pr.IsDraft = pr.PR.GetDraft()
-	pr.IAmAuthor = (pr.Author == ds.config.GithubUsername)
-	pr.Additions = pr.PR.GetAdditions()
-	pr.Deletions = pr.PR.GetDeletions()
+	pr.IAmAuthor = (pr.Author == me)
 	pr.CodeDelta = pr.Additions + pr.Deletions
 
-	logger.Shared().Printf("PR: %s/%s/%d  A:%d   D:%d\n", orgName, repoName, *pr.PR.Number, pr.Additions, pr.Deletions)
+	// cached PRs are recalculated from a copy so start every flag from scratch
+	pr.AuthorIsTeammate = false
+	pr.AuthorIsBot = false
+	pr.HasCommentsFromMe = false
+	pr.LastCommentFromMe = false
+	pr.HasChangesAfterLastComment = false
+	pr.IsApproved = false
+
+	logger.Shared().Printf("PR: %s/%s/%d  A:%d   D:%d\n", pr.OrgName, pr.RepoName, pr.Number, pr.Additions, pr.Deletions)
 
 	for _, n := range ds.config.TeamUsernames {
 		if n == pr.Author {
@@ -81,45 +168,37 @@ func (pr *PullRequest) calculateStatusFields(orgName string, repoName string, ds
 
 	// Comments
 	// start the comment time at the PR creation time
-	lastCommentTime := *pr.PR.CreatedAt.GetTime()
-	for _, c := range pr.Comments {
-		if lastCommentTime.Before(*c.CreatedAt.GetTime()) {
-			lastCommentTime = *c.CreatedAt.GetTime()
+	pr.Activity = pr.buildActivity()
+	lastCommentTime := pr.CreatedAt
+	for _, a := range pr.Activity {
+		if !lastCommentTime.After(a.CreatedAt) {
+			lastCommentTime = a.CreatedAt
 
 			// mark if the most recent comment is from me
-			if c.User.GetLogin() == ds.config.GithubUsername {
-				pr.LastCommentFromMe = true
-			}
+			pr.LastCommentFromMe = a.Author == me
 		}
 
 		// mark if I have commented on this PR
-		if c.User.GetLogin() == ds.config.GithubUsername {
+		if a.Author == me {
 			pr.HasCommentsFromMe = true
-			break
 		}
 	}
 	pr.LastCommentTime = lastCommentTime
-	pr.TimeSinceLastComment = time.Since(lastCommentTime)
 
 	// Commits
-	firstCommitTime := *pr.PR.CreatedAt.GetTime()
-	lastCommitTime := *pr.PR.CreatedAt.GetTime()
+	firstCommitTime := pr.CreatedAt
+	lastCommitTime := pr.CreatedAt
 	for _, c := range pr.Commits {
-		if firstCommitTime.After(*c.Commit.Committer.Date.GetTime()) {
-			firstCommitTime = *c.Commit.Committer.Date.GetTime()
+		if firstCommitTime.After(c.CommittedAt) {
+			firstCommitTime = c.CommittedAt
 		}
-		if lastCommitTime.Before(*c.Commit.Committer.Date.GetTime()) {
-			lastCommitTime = *c.Commit.Committer.Date.GetTime()
+		if lastCommitTime.Before(c.CommittedAt) {
+			lastCommitTime = c.CommittedAt
 		}
 	}
 	pr.FirstCommitTime = firstCommitTime
 	pr.LastCommitTime = lastCommitTime
-	pr.TimeSinceLastCommit = time.Since(lastCommitTime)
-	pr.TimeSinceFirstCommit = time.Since(firstCommitTime)
-
-	// Abandoned
-	abandonThresholdTime := lastCommitTime.Add(time.Duration(21) * time.Hour * time.Duration(24))
-	pr.IsAbandoned = time.Now().After(abandonThresholdTime)
+	pr.calculateTimeFields(ds, time.Now())
 
 	// clear viewed at if there are new changes
 	if pr.ViewedAt != nil {
@@ -128,43 +207,135 @@ func (pr *PullRequest) calculateStatusFields(orgName string, repoName string, ds
 		}
 	}
 
-	/*
-		// TODO : PULL THIS OUT TO NOT BE READ EVERY TIME!!!
-		abandonAgeDays := os.Getenv("ABANDONED_AGE_DAYS")
-		if days, err := strconv.Atoi(abandonAgeDays); err == nil {
-			pr.IsAbandoned = time.Now().After(pr.LastCommitTime.Add(time.Duration(21) * time.Hour * time.Duration(24)))
+	// Threads
+	pr.Threads = pr.buildThreads(me)
+	pr.UnansweredThreads = 0
+	for _, t := range pr.Threads {
+		if t.IsResolved || t.LastReplyFromMe {
+			continue
+		}
+		involved := pr.IAmAuthor
+		for _, c := range t.Comments {
+			if c.Author == me {
+				involved = true
+			}
+		}
+		if involved {
+			pr.UnansweredThreads++
+		}
+	}
+
+	// Review requests
+	pr.IAmRequested = false
+	for _, r := range pr.RequestedReviewers {
+		if strings.EqualFold(r, me) {
+			pr.IAmRequested = true
+		}
+	}
+	pr.IAmRequestedViaTeam = false
+	for _, t := range pr.RequestedTeams {
+		if ds.isMeOrMyTeam(me, t) {
+			pr.IAmRequestedViaTeam = true
 		}
-	*/
+	}
+
+	// Code owners
+	// keep the last known value until the repo's CODEOWNERS has been fetched
+	if owners, ok := ds.codeOwnersFor(pr); ok {
+		pr.IAmOwner = false
+		for _, path := range pr.ChangedFiles {
+			for _, owner := range owners.OwnersOf(path) {
+				if ds.isMeOrMyTeam(me, owner) {
+					pr.IAmOwner = true
+				}
+			}
+		}
+	}
+
+	// CI
+	pr.ChecksPassing = pr.ChecksState == CheckStateSuccess
+	pr.ChecksPending = pr.ChecksState == CheckStatePending
+	pr.ChecksFailing = pr.ChecksState == CheckStateFailure
 
 	// flag if we have unreviewed changes
-	if len(pr.Comments) == 0 || lastCommentTime.Before(lastCommitTime) {
+	if len(pr.Activity) == 0 || lastCommentTime.Before(lastCommitTime) {
 		pr.HasChangesAfterLastComment = true
 	}
 
-	for i := range pr.PR.Labels {
-		l := pr.PR.Labels[i]
-		pr.Labels = append(pr.Labels, *l.Name)
-	}
+	pr.calculateApprovals(ds.requiredApprovalsFor(pr))
+	pr.Turn = pr.calculateTurn(ds.config.BotUsernames)
+}
 
-	for i := range pr.PR.RequestedReviewers {
-		r := pr.PR.RequestedReviewers[i]
-		pr.RequestedReviewers = append(pr.RequestedReviewers, *r.Login)
+// The fields measured up to now. They go stale while the PR sits in the
+// cache so they are recalculated whenever the PR is read, see current.
+func (pr *PullRequest) calculateTimeFields(ds *Datasource, now time.Time) {
+	pr.TimeSinceLastComment = now.Sub(pr.LastCommentTime)
+	pr.TimeSinceLastCommit = now.Sub(pr.LastCommitTime)
+	pr.TimeSinceFirstCommit = now.Sub(pr.FirstCommitTime)
+
+	// most recent commit or comment
+	pr.TimeSinceLastActivity = pr.TimeSinceLastCommit
+	if pr.TimeSinceLastComment < pr.TimeSinceLastActivity {
+		pr.TimeSinceLastActivity = pr.TimeSinceLastComment
 	}
 
+	pr.BusinessTimeSinceLastComment = ds.calendar.between(pr.LastCommentTime, now)
+	pr.BusinessTimeSinceLastCommit = ds.calendar.between(pr.LastCommitTime, now)
+
+	// Abandoned
+	abandonThresholdTime := pr.LastCommitTime.AddDate(0, 0, ds.abandonedAgeDays(pr.OrgName, pr.RepoName))
+	pr.IsAbandoned = now.After(abandonThresholdTime)
+}
+
+// Merges inline review comments, conversation comments and review
+// submissions into one timeline sorted oldest first
+func (pr *PullRequest) buildActivity() []*Activity {
+	activity := []*Activity{}
+	for _, c := range pr.Comments {
+		activity = append(activity, &Activity{
+			Kind:      ActivityReviewComment,
+			Author:    c.Author,
+			Body:      c.Body,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	for _, c := range pr.IssueComments {
+		activity = append(activity, &Activity{
+			Kind:      ActivityIssueComment,
+			Author:    c.Author,
+			Body:      c.Body,
+			CreatedAt: c.CreatedAt,
+		})
+	}
 	for _, r := range pr.Reviews {
-		if *r.State == "APPROVED" {
-			pr.IsApproved = true
+		// pending reviews have not been submitted and a bare COMMENTED review
+		// is only the container for inline comments counted above
+		if r.SubmittedAt.IsZero() || (r.State == "COMMENTED" && len(r.Body) == 0) {
+			continue
 		}
-	}
+		activity = append(activity, &Activity{
+			Kind:      ActivityReview,
+			Author:    r.Author,
+			Body:      r.Body,
+			State:     r.State,
+			CreatedAt: r.SubmittedAt,
+		})
+	}
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].CreatedAt.Before(activity[j].CreatedAt)
+	})
+	return activity
 }
 
-// All importance values are normilized to 0-100
+// Importance is the sum of the scored features. Gating states like
+// abandoned, approved and draft short circuit the scoring.
 func (pr *PullRequest) calculateImportance(ds *Datasource) {
 	pr.ImportanceLookup = make(map[string]float64)
+	// rescored PRs may have become abandoned or approved since
+	pr.Importance = 0
 	importance := 0.0
 
 	// TODOs:
-	// add importance if i am CODEOWNER
 	// Sort by most recent activity first.
 
 	// if this pr is abandoned then we don't need to look at it
@@ -181,7 +352,7 @@ func (pr *PullRequest) calculateImportance(ds *Datasource) {
 
 	// if it is mine and approved we should go look at it
 	if pr.IAmAuthor && pr.IsApproved {
-		importance = math.MaxFloat64
+		pr.Importance = math.MaxFloat64
 		pr.ImportanceLookup["Ready"] = math.MaxFloat64
 		return
 	}
@@ -193,38 +364,71 @@ func (pr *PullRequest) calculateImportance(ds *Datasource) {
 		return
 	}
 
+	// the learned model replaces the configured weights entirely
+	if ds.model != nil {
+		pr.applyModel(ds.model)
+		return
+	}
+
+	// each feature's weight and curve come from scoring.yaml
+	addFeature := func(name string, x float64) {
+		imp := ds.scoring.Score(name, x)
+		importance += imp
+		pr.ImportanceLookup[name] = imp
+	}
+
 	// if I am NOT the author
 	if !pr.IAmAuthor {
-		importance += 100
-		pr.ImportanceLookup["Not mine"] = 100
+		addFeature(config.FeatureNotMine, 0)
+	}
+
+	// someone asked for my review directly or through one of my teams
+	if !pr.IAmAuthor && pr.IAmRequested {
+		addFeature(config.FeatureRequestedMe, 0)
+	} else if !pr.IAmAuthor && pr.IAmRequestedViaTeam {
+		addFeature(config.FeatureRequestedTeam, 0)
+	}
+
+	// if I own any of the changed code my review is likely required
+	if !pr.IAmAuthor && pr.IAmOwner {
+		addFeature(config.FeatureOwner, 0)
 	}
 
-	// if author is teammate add 50
+	// if author is teammate
 	if pr.AuthorIsTeammate {
-		importance += 100
-		pr.ImportanceLookup["Teammate"] = 100
+		addFeature(config.FeatureTeammate, 0)
 	}
 
-	// if the author is not a bot subtract 50
+	// if the author is a bot
 	if pr.AuthorIsBot {
-		importance -= 50
-		pr.ImportanceLookup["Not bot"] = -50
+		addFeature(config.FeatureBot, 0)
 	}
 
 	// More lines of code changed, the less important.
-	// THis is clamped between 0 and 100
-	codeImp := (1 / (float64(pr.CodeDelta) + 100)) * 1000
-	clampedCodeImp := clampFloat(codeImp, 0, 100)
-	importance += clampedCodeImp
-	pr.ImportanceLookup["Code delta"] = clampedCodeImp
+	addFeature(config.FeatureCodeDelta, float64(pr.CodeDelta))
 
 	// more requested reviewers the less important
 	if !pr.IAmAuthor {
-		revCount := float64(len(pr.RequestedReviewers))
-		imp := ((1 / (revCount + 5)) * 1000) * 0.5
-		clampedImp := clampFloat(imp, 0, 100)
-		importance += clampedImp
-		pr.ImportanceLookup["Reviewers"] = clampedImp
+		addFeature(config.FeatureReviewers, float64(len(pr.RequestedReviewers)))
+	}
+
+	// red or conflicting PRs are not ready for review yet but the author
+	// needs to go fix them
+	if pr.ChecksFailing && pr.IAmAuthor {
+		addFeature(config.FeatureFixChecks, 0)
+	} else if pr.ChecksFailing {
+		addFeature(config.FeatureChecksFailing, 0)
+	}
+
+	if pr.HasConflicts && pr.IAmAuthor {
+		addFeature(config.FeatureFixConflicts, 0)
+	} else if pr.HasConflicts {
+		addFeature(config.FeatureConflicts, 0)
+	}
+
+	// still running, it may go red before anyone gets to it
+	if !pr.IAmAuthor && pr.ChecksPending {
+		addFeature(config.FeatureChecksPending, 0)
 	}
 
 	// removing for now. this seems to give a bad signal
@@ -234,305 +438,33 @@ func (pr *PullRequest) calculateImportance(ds *Datasource) {
 
 	// min since last commit. if I am NOT the author but i have commented
 	// this is a high importance signal
+	minSinceLastCommit := float64(pr.BusinessTimeSinceLastCommit / time.Minute)
 	if !pr.IAmAuthor && pr.HasCommentsFromMe {
-		minSinceLastCommit := pr.TimeSinceLastCommit / time.Minute
-		// at 48hrs hrs the importance is 100
-		imp := math.Pow(float64(minSinceLastCommit), 2) / 80000
-		clampedImp := clampFloat(imp, 0, 250) // 250 pushes this signal up
-		importance += clampedImp
-		pr.ImportanceLookup["Change replies"] = clampedImp
+		addFeature(config.FeatureChangeReplies, minSinceLastCommit)
 	}
 
 	// min since last commit. if I am NOT the author and I haven't commented
 	if !pr.IAmAuthor && !pr.HasCommentsFromMe {
-		minSinceLastCommit := pr.TimeSinceLastCommit / time.Minute
-		// at 48hrs hrs the importance is 100
-		imp := math.Pow(float64(minSinceLastCommit), 2) / 80000
-		clampedImp := clampFloat(imp, 0, 50) // this should get looked at...
-		importance += clampedImp
-		pr.ImportanceLookup["Recent changes"] = clampedImp
+		addFeature(config.FeatureRecentChanges, minSinceLastCommit)
 	}
 
 	// min since last comment if I AM the author
 	// i should rapidly respond to comments
 	if pr.IAmAuthor && !pr.LastCommentFromMe {
-		minSinceLastComment := pr.TimeSinceLastComment / time.Minute
-		// at 24 hrs day the importance is 100
-		imp := math.Pow(float64(minSinceLastComment), 2) / 20000
-		clampedImp := clampFloat(imp, 0, 300) // push this signal up high
-		importance += clampedImp
-		pr.ImportanceLookup["Recent comment"] = clampedImp
+		addFeature(config.FeatureRecentComment, float64(pr.BusinessTimeSinceLastComment/time.Minute))
 	}
 
 	pr.Importance = importance
 }
 
-func clampFloat(val float64, min float64, max float64) float64 {
-	if val < min {
-		return min
-	}
-	if val > max {
-		return max
-	}
-	return val
-}
-
-func (ds *Datasource) UpdateExistingPr(org string, repo string, pr *PullRequest, ghpr *github.PullRequest) error {
-	// Store a reference to the github PR on our envelope for easy access later
-	fullGHPR, err := ds.GetPull(org, repo, *ghpr.Number, *ghpr.Number)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return err
-	}
-	// ensure we have the latest extended PR info (additions/deletions)
-	pr.PR = fullGHPR
-
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d updating commits...", org, repo, *ghpr.Number))
-	commits, lastCommitsPage, err := ds.GetAllCommitsForPull(org, repo, *ghpr.Number, pr.LastCommitsPage)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return err
-	}
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d updating comments...", org, repo, *ghpr.Number))
-	comments, lastCommentsPage, err := ds.GetAllCommentsForPull(org, repo, *ghpr.Number, pr.LastCommentsPage)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return err
-	}
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d updating reviews...", org, repo, *ghpr.Number))
-	reviews, lastReviewsPage, err := ds.GetAllReviewsForPull(org, repo, *ghpr.Number, pr.LastReviewsPage)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return err
-	}
-
-	// ugh...n^2 * 3 .... boooooo
-	// todo : refactor
-	for _, new := range commits {
-		found := false
-		for _, c := range pr.Commits {
-			if c.GetNodeID() == new.GetNodeID() {
-				found = true
-				break
-			}
-		}
-		if !found {
-			pr.Commits = append(pr.Commits, new)
-		}
-	}
-
-	for _, new := range comments {
-		found := false
-		for _, c := range pr.Comments {
-			if c.GetNodeID() == new.GetNodeID() {
-				found = true
-				break
-			}
-		}
-		if !found {
-			pr.Comments = append(pr.Comments, new)
-		}
-	}
-
-	for _, new := range reviews {
-		found := false
-		for _, c := range pr.Reviews {
-			if c.GetNodeID() == new.GetNodeID() {
-				found = true
-				break
-			}
-		}
-		if !found {
-			pr.Reviews = append(pr.Reviews, new)
-		}
-	}
-
-	pr.LastCommitsPage = lastCommitsPage
-	pr.LastCommentsPage = lastCommentsPage
-	pr.LastReviewsPage = lastReviewsPage
-
-	pr.calculateStatusFields(org, repo, ds)
-	pr.calculateImportance(ds)
-
-	return nil
-}
-
-func (ds *Datasource) BuildPullRequest(org string, repo string, ghpr *github.PullRequest) (*PullRequest, error) {
-	fullGHPR, err := ds.GetPull(org, repo, *ghpr.Number, *ghpr.Number)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return nil, err
-	}
-
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching commits...", org, repo, *ghpr.Number))
-	commits, lastCommitsPage, err := ds.GetAllCommitsForPull(org, repo, *ghpr.Number, 0)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return nil, err
-	}
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching comments...", org, repo, *ghpr.Number))
-	comments, lastCommentsPage, err := ds.GetAllCommentsForPull(org, repo, *ghpr.Number, 0)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return nil, err
-	}
-	ds.writeStatus(fmt.Sprintf("%s/%s/#%d fetching reviews...", org, repo, *ghpr.Number))
-	reviews, lastReviewsPage, err := ds.GetAllReviewsForPull(org, repo, *ghpr.Number, 0)
-	if err != nil {
-		ds.writeErrorStatus(err)
-		return nil, err
-	}
-
-	newPR := &PullRequest{
-		PR:       fullGHPR,
-		Commits:  commits,
-		Comments: comments,
-		Reviews:  reviews,
-
-		LastCommitsPage:  lastCommitsPage,
-		LastCommentsPage: lastCommentsPage,
-		LastReviewsPage:  lastReviewsPage,
-	}
-	newPR.calculateStatusFields(org, repo, ds)
-	newPR.calculateImportance(ds)
-
-	return newPR, nil
-}
-
-func (ds *Datasource) GetAllPullsForRepoInOrg(orgName string, repoName string) ([]*github.PullRequest, error) {
-	ctx := context.Background()
-	opt := &github.PullRequestListOptions{
-		ListOptions: github.ListOptions{PerPage: 10},
-		State:       "open",
-	}
-	// page through to get all pulls for this repo
-	// NOTE: the returned PR data is not complete and needs to be directly fetched
-	// to hydrate fields like additions and deletions
-	var allPulls []*github.PullRequest
-	for {
-		logger.Shared().Printf("getting pulls for: [%s/%s] page:%d", orgName, repoName, opt.Page)
-		prs, resp, err := sharedClient().PullRequests.List(ctx, orgName, repoName, opt)
-
-		if _, ok := err.(*github.RateLimitError); ok {
-			logger.Shared().Printf("pulls for: [%s/%s] hit rate limit", orgName, repoName)
-			// TODO : add jitter
-			time.Sleep(time.Duration(5) * time.Second)
-			continue
-		}
-		if err != nil {
-			logger.Shared().Printf("error getting pulls in [%s/%s] %s", orgName, repoName, err)
-			return allPulls, err
-		}
-
-		ds.remainingRequestsChan <- resp.Rate
-		allPulls = append(allPulls, prs...)
-		if resp.NextPage == 0 || opt.Page == resp.NextPage {
-			break
-		}
-		opt.Page = resp.NextPage
-	}
-	return allPulls, nil
-}
-
-func (ds *Datasource) GetPull(org string, repo string, prNumber int, lastPage int) (*github.PullRequest, error) {
-	ctx := context.Background()
-	fullPR, _, err := sharedClient().PullRequests.Get(ctx, org, repo, prNumber)
-	return fullPR, err
-}
-
-func (ds *Datasource) GetAllCommitsForPull(org string, repo string, prNumber int, lastPage int) ([]*github.RepositoryCommit, int, error) {
-	ctx := context.Background()
-	opt := &github.ListOptions{
-		PerPage: 10,
-		Page:    lastPage,
-	}
-	// get all pages of results
-	var allCommits []*github.RepositoryCommit
-	for {
-		logger.Shared().Printf("commits: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
-		commits, resp, err := sharedClient().PullRequests.ListCommits(ctx, org, repo, prNumber, opt)
-		if _, ok := err.(*github.RateLimitError); ok {
-			logger.Shared().Println("commits: hit rate limit")
-			time.Sleep(time.Duration(5) * time.Second)
-			continue
-		}
-		if err != nil {
-			logger.Shared().Printf("commits: error %s", err)
-			return allCommits, lastPage, err
-		}
-		ds.remainingRequestsChan <- resp.Rate
-		allCommits = append(allCommits, commits...)
-		if resp.NextPage == 0 || opt.Page == resp.NextPage {
-			break
-		}
-		opt.Page = resp.NextPage
-		lastPage = resp.NextPage
-	}
-	return allCommits, lastPage, nil
-}
-
-func (ds *Datasource) GetAllCommentsForPull(org string, repo string, prNumber int, lastPage int) ([]*github.PullRequestComment, int, error) {
-	ctx := context.Background()
-	opt := &github.PullRequestListCommentsOptions{
-		ListOptions: github.ListOptions{
-			PerPage: 10,
-			Page:    lastPage,
-		},
-	}
-	// get all pages of results
-	var allComments []*github.PullRequestComment
-	for {
-		logger.Shared().Printf("comments: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
-		comments, resp, err := sharedClient().PullRequests.ListComments(ctx, org, repo, prNumber, opt)
-		if _, ok := err.(*github.RateLimitError); ok {
-			logger.Shared().Println("comments: hit rate limit")
-			time.Sleep(time.Duration(5) * time.Second)
-			continue
-		}
-		if err != nil {
-			logger.Shared().Printf("comments: error %s", err)
-			return allComments, lastPage, err
-		}
-		ds.remainingRequestsChan <- resp.Rate
-		allComments = append(allComments, comments...)
-		if resp.NextPage == 0 || opt.Page == resp.NextPage {
-			break
-		}
-		opt.Page = resp.NextPage
-		lastPage = resp.NextPage
-	}
-	return allComments, lastPage, nil
-}
-
-func (ds *Datasource) GetAllReviewsForPull(org string, repo string, prNumber int, lastPage int) ([]*github.PullRequestReview, int, error) {
-	ctx := context.Background()
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{
-			PerPage: 10,
-			Page:    lastPage,
-		},
-		Type: "all",
-	}
-	// get all pages of results
-	var allReviews []*github.PullRequestReview
-	for {
-		logger.Shared().Printf("reviews: %s/%s/%d p:%d", org, repo, prNumber, opt.Page)
-		reviews, resp, err := sharedClient().PullRequests.ListReviews(ctx, org, repo, prNumber, nil)
-		if _, ok := err.(*github.RateLimitError); ok {
-			logger.Shared().Println("reviews: hit rate limit")
-			time.Sleep(time.Duration(5) * time.Second)
-			continue
-		}
-		if err != nil {
-			logger.Shared().Printf("reviews: error %s", err)
-			return allReviews, lastPage, err
-		}
-		allReviews = append(allReviews, reviews...)
-		if resp.NextPage == 0 || opt.Page == resp.NextPage {
-			break
-		}
-		opt.Page = resp.NextPage
-		lastPage = resp.NextPage
+// Scores with the model trained by `prty train`. Log odds are scaled up so
+// the breakdown reads like the hand tuned weights.
+func (pr *PullRequest) applyModel(m *Model) {
+	const scale = 100
+	z, contributions := m.Score(pr.modelFeatures())
+	for i, name := range m.Features {
+		pr.ImportanceLookup[name] = contributions[i] * scale
 	}
-	return allReviews, lastPage, nil
+	pr.ImportanceLookup["Model bias"] = m.Bias * scale
+	pr.Importance = z * scale
 }
@@ -7,9 +7,7 @@ import (
 	"github.com/inburst/prty/logger"
 )
 
-func GetAllReposForOrg(orgName string) ([]*github.Repository, error) {
-	ctx := context.Background()
-
+func (g *githubProvider) listReposForOrg(ctx context.Context, orgName string) ([]*github.Repository, error) {
 	opt := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{PerPage: 10},
 		Type:        "all",
@@ -18,7 +16,11 @@ func GetAllReposForOrg(orgName string) ([]*github.Repository, error) {
 	// get all pages of results
 	var allRepos []*github.Repository
 	for {
-		repos, resp, err := sharedClient().Repositories.ListByOrg(ctx, orgName, opt)
+		var repos []*github.Repository
+		resp, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+			repos, resp, err = sharedClient().Repositories.ListByOrg(ctx, orgName, opt)
+			return
+		})
 		if err != nil {
 			logger.Shared().Printf("repos err for org [%s]: %s\n", orgName, err)
 			return allRepos, err
@@ -34,9 +36,12 @@ func GetAllReposForOrg(orgName string) ([]*github.Repository, error) {
 	return allRepos, nil
 }
 
-func GetRepoInOrg(orgName string, repoName string) (*github.Repository, error) {
-	ctx := context.Background()
-	repo, _, err := sharedClient().Repositories.Get(ctx, orgName, repoName)
+func (g *githubProvider) getRepoInOrg(ctx context.Context, orgName string, repoName string) (*github.Repository, error) {
+	var repo *github.Repository
+	_, err := g.ds.scheduler.do(ctx, func() (resp *github.Response, err error) {
+		repo, resp, err = sharedClient().Repositories.Get(ctx, orgName, repoName)
+		return
+	})
 	if err != nil {
 		logger.Shared().Printf("repo err for [%s/%s]: %s\n", orgName, repoName, err)
 		return nil, err
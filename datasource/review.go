@@ -0,0 +1,66 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inburst/prty/logger"
+	"github.com/inburst/prty/tracking"
+)
+
+// Posts a review on the PR then fetches and scores it again so the views
+// reflect the new state. Blocks until both are done.
+func (ds *Datasource) SubmitReview(pr *PullRequest, event string, body string) error {
+	provider := ds.providerFor(pr)
+	rp, ok := provider.(reviewProvider)
+	if !ok {
+		err := fmt.Errorf("reviews are not supported for %s", pr.Provider)
+		ds.writeErrorStatus(err)
+		return err
+	}
+
+	ctx := context.Background()
+	ds.writeStatus(fmt.Sprintf("%s/%s/#%d submitting %s...", pr.OrgName, pr.RepoName, pr.Number, strings.ToLower(event)))
+	if err := rp.SubmitReview(ctx, pr, event, body); err != nil {
+		ds.writeErrorStatus(err)
+		logger.Shared().Printf("error submitting review %s\n", err)
+		tracking.SendMetric("data.submitreview.error")
+		return err
+	}
+
+	// hydrate a copy so the views never see a half updated PR. Fetched
+	// pages are appended to the lists so they get their own backing arrays.
+	refreshed := *pr
+	refreshed.copyLists(pr)
+	refreshed.hydrated = false
+	if ds.buildPr(ctx, provider, &refreshed) {
+		ds.SaveToFile()
+	}
+	return nil
+}
+
+// Fetches the diff of every file the PR changes. Diffs are only needed while
+// reading a PR so they are not cached.
+func (ds *Datasource) GetFileDiffs(pr *PullRequest) ([]*FileDiff, error) {
+	dp, ok := ds.providerFor(pr).(diffProvider)
+	if !ok {
+		return nil, fmt.Errorf("diffs are not supported for %s", pr.Provider)
+	}
+	files, err := dp.GetFileDiffs(context.Background(), pr)
+	if err != nil {
+		logger.Shared().Printf("error getting diffs %s\n", err)
+		tracking.SendMetric("data.getdiffs.error")
+		return nil, err
+	}
+	return files, nil
+}
+
+func (ds *Datasource) providerFor(pr *PullRequest) Provider {
+	for _, p := range ds.providers {
+		if p.Name() == pr.Provider {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+)
+
+// reviewingProvider adds each submitted review to the PR the next time it
+// is hydrated, the way fetching the newest page does
+type reviewingProvider struct {
+	fakeProvider
+	submitted []*Review
+}
+
+func (f *reviewingProvider) SubmitReview(ctx context.Context, pr *PullRequest, event string, body string) error {
+	f.submitted = append(f.submitted, &Review{State: event, Body: body, SubmittedAt: time.Now()})
+	return nil
+}
+
+func (f *reviewingProvider) HydratePull(ctx context.Context, pr *PullRequest) error {
+	pr.Reviews = append(pr.Reviews, f.submitted[len(f.submitted)-1])
+	return nil
+}
+
+var _ = Describe("SubmitReview", func() {
+	var ds *Datasource
+
+	BeforeEach(func() {
+		ds = New(&config.Config{GithubUsername: "me"})
+		statusChan := make(chan string)
+		prUpdateChan := make(chan *PullRequest)
+		ds.SetStatusChan(statusChan)
+		ds.SetPRUpdateChan(prUpdateChan)
+		go func() {
+			for range statusChan {
+			}
+		}()
+		go func() {
+			for range prUpdateChan {
+			}
+		}()
+	})
+
+	Context("Datasource", func() {
+		It("should not share the lists of the PR on screen with the refreshed ones", func() {
+			provider := &reviewingProvider{}
+			ds.providers = []Provider{provider}
+
+			// room to grow so appending in place would reuse the array
+			onScreen := provider.pull("a")
+			onScreen.Reviews = make([]*Review, 0, 4)
+
+			Expect(ds.SubmitReview(onScreen, ReviewEventApprove, "")).To(Succeed())
+			first := ds.allPRs["a"]
+			Expect(ds.SubmitReview(onScreen, ReviewEventComment, "again")).To(Succeed())
+
+			Expect(onScreen.Reviews).To(BeEmpty())
+			Expect(first.Reviews).To(HaveLen(1))
+			Expect(first.Reviews[0].State).To(Equal(ReviewEventApprove))
+			Expect(ds.allPRs["a"].Reviews[0].State).To(Equal(ReviewEventComment))
+		})
+
+		It("should fail for providers that can not review", func() {
+			provider := &fakeProvider{}
+			ds.providers = []Provider{provider}
+			Expect(ds.SubmitReview(provider.pull("a"), ReviewEventApprove, "")).NotTo(Succeed())
+		})
+	})
+
+	Context("githubProvider", func() {
+		type request struct {
+			Method string
+			Path   string
+			Body   map[string]interface{}
+		}
+
+		var (
+			server   *httptest.Server
+			previous *github.Client
+			provider *githubProvider
+			pr       *PullRequest
+			requests []request
+		)
+
+		BeforeEach(func() {
+			requests = []request{}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := map[string]interface{}{}
+				json.NewDecoder(r.Body).Decode(&body)
+				requests = append(requests, request{r.Method, r.URL.Path, body})
+				w.Write([]byte("{}"))
+			}))
+
+			previous = sharedGithubClient
+			sharedGithubClient = github.NewClient(nil)
+			sharedGithubClient.BaseURL, _ = url.Parse(server.URL + "/")
+
+			provider = newGithubProvider(ds, "me")
+			pr = &PullRequest{OrgName: "org", RepoName: "repo", Number: 7}
+		})
+
+		AfterEach(func() {
+			sharedGithubClient = previous
+			server.Close()
+		})
+
+		It("should approve with a review", func() {
+			Expect(provider.SubmitReview(context.Background(), pr, ReviewEventApprove, "")).To(Succeed())
+			Expect(requests).To(Equal([]request{{
+				Method: "POST",
+				Path:   "/repos/org/repo/pulls/7/reviews",
+				Body:   map[string]interface{}{"event": "APPROVE"},
+			}}))
+		})
+
+		It("should request changes with a review", func() {
+			Expect(provider.SubmitReview(context.Background(), pr, ReviewEventRequestChanges, "please fix")).To(Succeed())
+			Expect(requests).To(Equal([]request{{
+				Method: "POST",
+				Path:   "/repos/org/repo/pulls/7/reviews",
+				Body:   map[string]interface{}{"event": "REQUEST_CHANGES", "body": "please fix"},
+			}}))
+		})
+
+		It("should comment on the conversation rather than review", func() {
+			Expect(provider.SubmitReview(context.Background(), pr, ReviewEventComment, "looks close")).To(Succeed())
+			Expect(requests).To(Equal([]request{{
+				Method: "POST",
+				Path:   "/repos/org/repo/issues/7/comments",
+				Body:   map[string]interface{}{"body": "looks close"},
+			}}))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/inburst/prty/logger"
+)
+
+const maxSecondaryLimitRetries = 5
+const baseBackoff = time.Second * 2
+const maxBackoff = time.Minute * 2
+
+// scheduler is the single place github calls go through. It tracks the most
+// recent rate info, holds every worker until the reset once the budget is
+// spent and retries primary and secondary (abuse) limit errors.
+type scheduler struct {
+	ds *Datasource
+	// the clock, replaced in tests
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+
+	mutex       sync.Mutex
+	rate        github.Rate
+	pausedUntil time.Time
+}
+
+func newScheduler(ds *Datasource) *scheduler {
+	return &scheduler{
+		ds:    ds,
+		now:   time.Now,
+		after: time.After,
+	}
+}
+
+func (s *scheduler) do(ctx context.Context, call func() (*github.Response, error)) (*github.Response, error) {
+	secondaryRetries := 0
+	for {
+		if err := s.waitForBudget(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := call()
+		if resp != nil {
+			s.observe(resp.Rate)
+		}
+
+		switch e := err.(type) {
+		case *github.RateLimitError:
+			logger.Shared().Printf("hit primary rate limit, resets at %s\n", e.Rate.Reset)
+			s.observe(e.Rate)
+			s.pauseUntil(e.Rate.Reset.Time.Add(jitter(time.Second * 5)))
+			continue
+
+		case *github.AbuseRateLimitError:
+			if secondaryRetries >= maxSecondaryLimitRetries {
+				return resp, err
+			}
+			wait := backoff(secondaryRetries)
+			if e.RetryAfter != nil {
+				wait = *e.RetryAfter + jitter(time.Second)
+			}
+			secondaryRetries++
+			logger.Shared().Printf("hit secondary rate limit, retry %d in %s\n", secondaryRetries, wait)
+			s.pauseUntil(s.now().Add(wait))
+			continue
+		}
+		return resp, err
+	}
+}
+
+// Latest rate info reported by github
+func (s *scheduler) Rate() github.Rate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.rate
+}
+
+func (s *scheduler) observe(rate github.Rate) {
+	// responses that carry no rate headers parse as an empty rate
+	if rate.Limit == 0 {
+		return
+	}
+	s.mutex.Lock()
+	s.rate = rate
+	if rate.Remaining == 0 && rate.Reset.After(s.pausedUntil) {
+		s.pausedUntil = rate.Reset.Time
+	}
+	s.mutex.Unlock()
+
+	// only the latest rate is shown so skip it rather than hold up the
+	// call when nobody is listening
+	select {
+	case s.ds.remainingRequestsChan <- rate:
+	default:
+	}
+}
+
+func (s *scheduler) pauseUntil(until time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+// Blocks while the scheduler is paused, waking early if the refresh is
+// cancelled
+func (s *scheduler) waitForBudget(ctx context.Context) error {
+	s.mutex.Lock()
+	until := s.pausedUntil
+	s.mutex.Unlock()
+
+	wait := until.Sub(s.now())
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	s.ds.writeStatus(fmt.Sprintf("rate limited, paused until %s", until.Local().Format("15:04")))
+	select {
+	case <-s.after(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Exponential backoff with full jitter
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << uint(attempt)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + jitter(d/2)
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,138 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scheduler", func() {
+	start := time.Date(2021, time.May, 24, 9, 0, 0, 0, time.UTC)
+
+	var (
+		s     *scheduler
+		now   time.Time
+		waits []time.Duration
+		calls int
+	)
+
+	BeforeEach(func() {
+		statusChan := make(chan string)
+		go func() {
+			for range statusChan {
+			}
+		}()
+		// nobody reads the rate so any send would block
+		s = newScheduler(&Datasource{
+			statusChan:            statusChan,
+			remainingRequestsChan: make(chan github.Rate),
+		})
+
+		now = start
+		waits = []time.Duration{}
+		calls = 0
+		// waiting moves the clock on instead of sleeping
+		s.now = func() time.Time { return now }
+		s.after = func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			now = now.Add(d)
+			c := make(chan time.Time, 1)
+			c <- now
+			return c
+		}
+	})
+
+	rate := func(remaining int, reset time.Time) github.Rate {
+		return github.Rate{Limit: 5000, Remaining: remaining, Reset: github.Timestamp{Time: reset}}
+	}
+	response := func(r github.Rate) *github.Response {
+		return &github.Response{Response: &http.Response{StatusCode: http.StatusOK}, Rate: r}
+	}
+
+	It("should record the rate without anyone listening", func() {
+		_, err := s.do(context.Background(), func() (*github.Response, error) {
+			calls++
+			return response(rate(4999, start.Add(time.Hour))), nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Rate().Remaining).To(Equal(4999))
+	})
+
+	It("should wait for the reset after a primary rate limit", func() {
+		reset := start.Add(time.Minute)
+		_, err := s.do(context.Background(), func() (*github.Response, error) {
+			calls++
+			if calls == 1 {
+				return nil, &github.RateLimitError{Rate: rate(0, reset)}
+			}
+			return response(rate(5000, reset.Add(time.Hour))), nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+		Expect(waits).To(HaveLen(1))
+		Expect(waits[0]).To(BeNumerically(">=", time.Minute))
+		Expect(waits[0]).To(BeNumerically("<", time.Minute+5*time.Second))
+	})
+
+	It("should hold later calls once the budget is spent", func() {
+		reset := start.Add(time.Minute)
+		s.do(context.Background(), func() (*github.Response, error) {
+			return response(rate(0, reset)), nil
+		})
+		s.do(context.Background(), func() (*github.Response, error) {
+			calls++
+			return response(rate(5000, reset.Add(time.Hour))), nil
+		})
+		Expect(calls).To(Equal(1))
+		Expect(waits).To(Equal([]time.Duration{time.Minute}))
+	})
+
+	It("should wait as long as Retry-After asks on a secondary rate limit", func() {
+		retryAfter := 30 * time.Second
+		_, err := s.do(context.Background(), func() (*github.Response, error) {
+			calls++
+			if calls == 1 {
+				return nil, &github.AbuseRateLimitError{RetryAfter: &retryAfter}
+			}
+			return response(rate(4999, start.Add(time.Hour))), nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+		Expect(waits).To(HaveLen(1))
+		Expect(waits[0]).To(BeNumerically(">=", retryAfter))
+		Expect(waits[0]).To(BeNumerically("<", retryAfter+time.Second))
+	})
+
+	It("should give up after repeated secondary rate limits", func() {
+		_, err := s.do(context.Background(), func() (*github.Response, error) {
+			calls++
+			return nil, &github.AbuseRateLimitError{}
+		})
+		var abuse *github.AbuseRateLimitError
+		Expect(errors.As(err, &abuse)).To(BeTrue())
+		Expect(calls).To(Equal(maxSecondaryLimitRetries + 1))
+		Expect(waits).To(HaveLen(maxSecondaryLimitRetries))
+		for _, wait := range waits {
+			Expect(wait).To(BeNumerically("<=", maxBackoff))
+		}
+	})
+
+	It("should stop waiting when the refresh is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.after = func(d time.Duration) <-chan time.Time { return nil }
+		s.pauseUntil(start.Add(time.Hour))
+
+		_, err := s.do(ctx, func() (*github.Response, error) {
+			calls++
+			return nil, nil
+		})
+		Expect(err).To(Equal(context.Canceled))
+		Expect(calls).To(Equal(0))
+	})
+})
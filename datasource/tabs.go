@@ -0,0 +1,96 @@
+package datasource
+
+import (
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/filter"
+	"github.com/inburst/prty/logger"
+)
+
+const defaultTabSort = "Importance desc"
+
+// The built in tabs. Each one is just a filter over the PR fields.
+var DefaultTabs = []config.TabConfig{
+	{
+		Name:   "Needs Attention",
+		Filter: "!IsAbandoned && !IsDraft && !IsApproved && !AuthorIsBot && HasChangesAfterLastComment",
+	},
+	{
+		Name:   "Requested",
+		Filter: "!IAmAuthor && (IAmRequested || IAmRequestedViaTeam) && !IsAbandoned",
+	},
+	{
+		Name:   "Team",
+		Filter: "(AuthorIsTeammate || IAmAuthor) && !IsApproved && !IsAbandoned",
+	},
+	{
+		Name:   "Mine",
+		Filter: "IAmAuthor && !IsApproved && !IsAbandoned",
+	},
+	{
+		// things updated within 48 hours are considered active, add a bit to
+		// spread over the weekend
+		Name:   "Active",
+		Filter: "!IsApproved && TimeSinceLastActivity < 60h",
+	},
+	{
+		Name:   "Bots",
+		Filter: "AuthorIsBot && !IsApproved && !IsAbandoned",
+	},
+	{
+		Name:   "All",
+		Filter: "!IsApproved",
+	},
+}
+
+// The default tabs, unless hidden, followed by the configured ones
+func TabConfigs(c *config.Config) []config.TabConfig {
+	tabs := []config.TabConfig{}
+	if !c.HideDefaultTabs {
+		tabs = append(tabs, DefaultTabs...)
+	}
+	return append(tabs, c.Tabs...)
+}
+
+// Parses the tab's filter and sort, checking every field they name exists
+func ParseTab(tab config.TabConfig) (*filter.Filter, *filter.Sort, error) {
+	f, err := filter.Parse(tab.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = f.Validate(&PullRequest{}); err != nil {
+		return nil, nil, err
+	}
+
+	sortSpec := tab.Sort
+	if len(sortSpec) == 0 {
+		sortSpec = defaultTabSort
+	}
+	sorter, err := filter.ParseSort(sortSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = sorter.Validate(&PullRequest{}); err != nil {
+		return nil, nil, err
+	}
+	return f, sorter, nil
+}
+
+// The open PRs matching the tab's filter in its sort order
+func FilterPulls(tab config.TabConfig, pulls []*PullRequest) ([]*PullRequest, error) {
+	f, sorter, err := ParseTab(tab)
+	if err != nil {
+		return nil, err
+	}
+	matching := []*PullRequest{}
+	for _, pr := range pulls {
+		matches, err := f.Match(pr)
+		if err != nil {
+			logger.Shared().Printf("tab [%s] %s\n", tab.Name, err)
+		}
+		if matches && !pr.IsClosed {
+			matching = append(matching, pr)
+		}
+	}
+	sorter.Apply(matching)
+	return matching, nil
+}
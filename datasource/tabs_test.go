@@ -0,0 +1,42 @@
+package datasource
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+)
+
+var _ = Describe("FilterPulls", func() {
+	ids := func(pulls []*PullRequest) []string {
+		out := []string{}
+		for _, pr := range pulls {
+			out = append(out, pr.ID)
+		}
+		return out
+	}
+
+	It("should return the open matches in the tab's sort order", func() {
+		pulls := []*PullRequest{
+			{ID: "low", IAmAuthor: true, Importance: 1},
+			{ID: "other", Importance: 5},
+			{ID: "high", IAmAuthor: true, Importance: 3},
+			{ID: "closed", IAmAuthor: true, Importance: 4, IsClosed: true},
+		}
+		matching, err := FilterPulls(config.TabConfig{Name: "Mine", Filter: "IAmAuthor"}, pulls)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(matching)).To(Equal([]string{"high", "low"}))
+	})
+
+	It("should reject filters on fields PRs do not have", func() {
+		_, err := FilterPulls(config.TabConfig{Name: "Bad", Filter: "NotAField"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should parse every default tab", func() {
+		for _, tab := range DefaultTabs {
+			_, _, err := ParseTab(tab)
+			Expect(err).NotTo(HaveOccurred(), tab.Name)
+		}
+	})
+})
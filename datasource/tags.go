@@ -0,0 +1,102 @@
+package datasource
+
+import (
+	"fmt"
+)
+
+// Tag kinds, each rendered in its own color by the UI and the dashboard
+const (
+	TagPlain   = "plain"
+	TagDefault = "default"
+	TagMuted   = "muted"
+	TagAlert   = "alert"
+	TagSuccess = "success"
+	TagPurple  = "purple"
+)
+
+// Tag is a label in a PR's footer. An empty label is not shown.
+type Tag struct {
+	Label string
+	Kind  string
+}
+
+// The turn is highlighted when the move is mine
+func (pr *PullRequest) StatusTag() Tag {
+	if pr.IsAbandoned {
+		return Tag{"ABANDONED 💀", TagMuted}
+	}
+	if pr.IsDraft {
+		return Tag{"DRAFT", TagDefault}
+	}
+	switch pr.Turn {
+	case TurnMerge:
+		return Tag{"READY TO MERGE", TagSuccess}
+	case TurnCI:
+		return Tag{"WAITING ON CI", TagDefault}
+	case TurnAuthor:
+		if pr.IAmAuthor {
+			return Tag{"WAITING ON AUTHOR", TagAlert}
+		}
+		return Tag{"WAITING ON AUTHOR", TagDefault}
+	default:
+		if !pr.IAmAuthor {
+			return Tag{"NEEDS REVIEW", TagAlert}
+		}
+		return Tag{"NEEDS REVIEW", TagDefault}
+	}
+}
+
+func (pr *PullRequest) OwnerTag() Tag {
+	if pr.IAmOwner {
+		return Tag{"OWNER", TagPurple}
+	}
+	return Tag{}
+}
+
+// Partial approvals, approvals on older commits are called out as stale
+func (pr *PullRequest) ApprovalsTag() Tag {
+	if pr.IsApproved || pr.ApprovalCount+pr.StaleApprovalCount == 0 {
+		return Tag{}
+	}
+	approvals := fmt.Sprintf("%d/%d approvals", pr.ApprovalCount, pr.RequiredApprovals)
+	if pr.StaleApprovalCount > 0 {
+		approvals += fmt.Sprintf(" (%d stale)", pr.StaleApprovalCount)
+	}
+	return Tag{approvals, TagPlain}
+}
+
+func (pr *PullRequest) ChecksTag() Tag {
+	if pr.ChecksFailing {
+		return Tag{"CHECKS FAILING", TagAlert}
+	} else if pr.ChecksPending {
+		return Tag{"CHECKS PENDING", TagDefault}
+	} else if pr.ChecksPassing {
+		return Tag{"CHECKS PASSING", TagSuccess}
+	}
+	return Tag{}
+}
+
+func (pr *PullRequest) ConflictsTag() Tag {
+	if pr.HasConflicts {
+		return Tag{"CONFLICTS", TagAlert}
+	}
+	return Tag{}
+}
+
+func (pr *PullRequest) OpenedTag() Tag {
+	if pr.ViewedAt != nil {
+		return Tag{"OPENED", TagSuccess}
+	}
+	return Tag{}
+}
+
+// Every tag shown in the PR's footer, in footer order
+func (pr *PullRequest) Tags() []Tag {
+	tags := []Tag{}
+	for _, t := range []Tag{pr.StatusTag(), pr.OwnerTag(), pr.ApprovalsTag(), pr.ChecksTag(), pr.ConflictsTag(), pr.OpenedTag()} {
+		if len(t.Label) > 0 {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,69 @@
+package datasource
+
+import (
+	"sort"
+)
+
+// Thread is a review comment and its replies on one line of the diff
+type Thread struct {
+	Path     string
+	Line     int
+	DiffHunk string
+	// oldest first, the first comment started the thread
+	Comments []*Comment
+
+	IsResolved bool
+	IsOutdated bool
+	// false when someone else replied last and I may need to respond
+	LastReplyFromMe bool
+}
+
+// Groups review comments into threads by the comment they reply to. Threads
+// are ordered by file then line so they read in the same order as the diff.
+func (pr *PullRequest) buildThreads(me string) []*Thread {
+	comments := make([]*Comment, len(pr.Comments))
+	copy(comments, pr.Comments)
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+
+	resolved := map[string]bool{}
+	for _, id := range pr.ResolvedThreads {
+		resolved[id] = true
+	}
+	outdated := map[string]bool{}
+	for _, id := range pr.OutdatedThreads {
+		outdated[id] = true
+	}
+
+	byRoot := map[string]*Thread{}
+	threads := []*Thread{}
+	for _, c := range comments {
+		// replies to a comment that was not fetched start their own thread
+		if t, ok := byRoot[c.InReplyTo]; ok && len(c.InReplyTo) > 0 {
+			t.Comments = append(t.Comments, c)
+			continue
+		}
+		t := &Thread{
+			Path:       c.Path,
+			Line:       c.Line,
+			DiffHunk:   c.DiffHunk,
+			Comments:   []*Comment{c},
+			IsResolved: resolved[c.ID],
+			IsOutdated: outdated[c.ID],
+		}
+		byRoot[c.ID] = t
+		threads = append(threads, t)
+	}
+
+	for _, t := range threads {
+		t.LastReplyFromMe = t.Comments[len(t.Comments)-1].Author == me
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Path != threads[j].Path {
+			return threads[i].Path < threads[j].Path
+		}
+		return threads[i].Line < threads[j].Line
+	})
+	return threads
+}
@@ -0,0 +1,65 @@
+package datasource
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("buildThreads", func() {
+	start := time.Date(2021, time.May, 24, 9, 0, 0, 0, time.UTC)
+	comment := func(id string, author string, replyTo string, path string, line int, hour int) *Comment {
+		return &Comment{
+			ID:        id,
+			Author:    author,
+			InReplyTo: replyTo,
+			Path:      path,
+			Line:      line,
+			CreatedAt: start.Add(time.Duration(hour) * time.Hour),
+		}
+	}
+
+	It("should group replies under the comment they answer", func() {
+		pr := &PullRequest{Comments: []*Comment{
+			comment("3", "me", "1", "a.go", 10, 3),
+			comment("1", "alice", "", "a.go", 10, 1),
+			comment("2", "bob", "", "a.go", 10, 2),
+		}}
+		threads := pr.buildThreads("me")
+		Expect(threads).To(HaveLen(2))
+		Expect(threads[0].Comments).To(HaveLen(2))
+		Expect(threads[0].Comments[0].ID).To(Equal("1"))
+		Expect(threads[0].LastReplyFromMe).To(BeTrue())
+		Expect(threads[1].Comments).To(HaveLen(1))
+		Expect(threads[1].LastReplyFromMe).To(BeFalse())
+	})
+
+	It("should order threads by file and line", func() {
+		pr := &PullRequest{Comments: []*Comment{
+			comment("1", "alice", "", "b.go", 1, 1),
+			comment("2", "alice", "", "a.go", 20, 2),
+			comment("3", "alice", "", "a.go", 5, 3),
+		}}
+		threads := pr.buildThreads("me")
+		Expect(threads[0].Comments[0].ID).To(Equal("3"))
+		Expect(threads[1].Comments[0].ID).To(Equal("2"))
+		Expect(threads[2].Comments[0].ID).To(Equal("1"))
+	})
+
+	It("should mark resolved and outdated threads by their first comment", func() {
+		pr := &PullRequest{
+			Comments: []*Comment{
+				comment("1", "alice", "", "a.go", 1, 1),
+				comment("2", "alice", "", "a.go", 2, 2),
+			},
+			ResolvedThreads: []string{"1"},
+			OutdatedThreads: []string{"2"},
+		}
+		threads := pr.buildThreads("me")
+		Expect(threads[0].IsResolved).To(BeTrue())
+		Expect(threads[0].IsOutdated).To(BeFalse())
+		Expect(threads[1].IsResolved).To(BeFalse())
+		Expect(threads[1].IsOutdated).To(BeTrue())
+	})
+})
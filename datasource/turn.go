@@ -0,0 +1,106 @@
+package datasource
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Whose move it is on a PR
+const (
+	TurnReviewer = "reviewer"
+	TurnAuthor   = "author"
+	TurnCI       = "ci"
+	TurnMerge    = "merge"
+)
+
+// Walks the PR timeline to decide who is blocking it. In order:
+//   - drafts, conflicts, failing checks and unanswered change requests are
+//     on the author
+//   - pending checks are on CI
+//   - approved PRs are ready to merge
+//   - outstanding review requests, including re-requests, are on the reviewer
+//   - otherwise whoever acted last is waiting on the other side
+func (pr *PullRequest) calculateTurn(bots []string) string {
+	isBot := func(login string) bool {
+		for _, b := range bots {
+			if strings.EqualFold(b, login) {
+				return true
+			}
+		}
+		return false
+	}
+
+	lastAuthorAction := pr.CreatedAt
+	for _, c := range pr.Commits {
+		if c.CommittedAt.After(lastAuthorAction) {
+			lastAuthorAction = c.CommittedAt
+		}
+	}
+	lastCommit := lastAuthorAction
+
+	lastReviewerAction := time.Time{}
+	for _, a := range pr.Activity {
+		// dismissed reviews no longer count as feedback
+		if a.Kind == ActivityReview && a.State == "DISMISSED" {
+			continue
+		}
+		if a.Author == pr.Author {
+			if a.CreatedAt.After(lastAuthorAction) {
+				lastAuthorAction = a.CreatedAt
+			}
+		} else if !isBot(a.Author) {
+			if a.CreatedAt.After(lastReviewerAction) {
+				lastReviewerAction = a.CreatedAt
+			}
+		}
+	}
+
+	// change requests stand until the reviewer approves, is dismissed or
+	// new commits are pushed for them to look at
+	changesRequested := false
+	for _, r := range pr.latestReviews() {
+		if r.State == "CHANGES_REQUESTED" && !lastCommit.After(r.SubmittedAt) {
+			changesRequested = true
+		}
+	}
+
+	switch {
+	case pr.IsDraft, pr.HasConflicts, pr.ChecksFailing, changesRequested:
+		return TurnAuthor
+	case pr.ChecksPending:
+		return TurnCI
+	case pr.IsApproved:
+		return TurnMerge
+	case len(pr.RequestedReviewers) > 0 || len(pr.RequestedTeams) > 0:
+		return TurnReviewer
+	case lastReviewerAction.After(lastAuthorAction):
+		return TurnAuthor
+	}
+	return TurnReviewer
+}
+
+// The most recent approval, change request or dismissal from each reviewer,
+// keyed by login. Plain comments do not change a reviewer's verdict. Gitlab
+// approvals may have no time, they still count but sort first.
+func (pr *PullRequest) latestReviews() map[string]*Review {
+	reviews := make([]*Review, 0, len(pr.Reviews))
+	for _, r := range pr.Reviews {
+		if r.Author == pr.Author {
+			continue
+		}
+		switch r.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			reviews = append(reviews, r)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].SubmittedAt.Before(reviews[j].SubmittedAt)
+	})
+
+	latest := map[string]*Review{}
+	for _, r := range reviews {
+		latest[r.Author] = r
+	}
+	return latest
+}
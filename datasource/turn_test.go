@@ -0,0 +1,75 @@
+package datasource
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("calculateTurn", func() {
+	start := time.Date(2021, time.May, 24, 9, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return start.Add(time.Duration(hour) * time.Hour)
+	}
+
+	var pr *PullRequest
+	BeforeEach(func() {
+		pr = &PullRequest{
+			Author:    "author",
+			CreatedAt: start,
+			Commits:   []*Commit{{SHA: "a", CommittedAt: at(0)}},
+		}
+	})
+	turn := func() string {
+		pr.Activity = pr.buildActivity()
+		return pr.calculateTurn([]string{"ci-bot"})
+	}
+
+	It("should wait on a reviewer for a fresh PR", func() {
+		Expect(turn()).To(Equal(TurnReviewer))
+	})
+
+	It("should wait on the author after a reviewer comments", func() {
+		pr.IssueComments = []*Comment{{Author: "reviewer", CreatedAt: at(1)}}
+		Expect(turn()).To(Equal(TurnAuthor))
+	})
+
+	It("should ignore bot comments", func() {
+		pr.IssueComments = []*Comment{{Author: "ci-bot", CreatedAt: at(1)}}
+		Expect(turn()).To(Equal(TurnReviewer))
+	})
+
+	It("should wait on the author until requested changes are pushed", func() {
+		pr.Reviews = []*Review{{Author: "reviewer", State: "CHANGES_REQUESTED", SubmittedAt: at(1)}}
+		pr.IssueComments = []*Comment{{Author: "author", CreatedAt: at(2)}}
+		Expect(turn()).To(Equal(TurnAuthor))
+
+		pr.Commits = append(pr.Commits, &Commit{SHA: "b", CommittedAt: at(3)})
+		Expect(turn()).To(Equal(TurnReviewer))
+	})
+
+	It("should drop dismissed change requests", func() {
+		pr.Reviews = []*Review{{Author: "reviewer", State: "DISMISSED", SubmittedAt: at(1)}}
+		Expect(turn()).To(Equal(TurnReviewer))
+	})
+
+	It("should wait on a re-requested reviewer", func() {
+		pr.IssueComments = []*Comment{{Author: "reviewer", CreatedAt: at(1)}}
+		pr.RequestedReviewers = []string{"reviewer"}
+		Expect(turn()).To(Equal(TurnReviewer))
+	})
+
+	It("should wait on CI and then merge once approved", func() {
+		pr.Reviews = []*Review{{Author: "reviewer", State: "APPROVED", SubmittedAt: at(1)}}
+		pr.IsApproved = true
+		pr.ChecksPending = true
+		Expect(turn()).To(Equal(TurnCI))
+
+		pr.ChecksPending = false
+		Expect(turn()).To(Equal(TurnMerge))
+
+		pr.ChecksFailing = true
+		Expect(turn()).To(Equal(TurnAuthor))
+	})
+})
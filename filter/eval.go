@@ -0,0 +1,234 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type literalNode struct {
+	v interface{}
+}
+
+func (n *literalNode) eval(v interface{}) (interface{}, error) {
+	return n.v, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(v interface{}) (interface{}, error) {
+	return fieldValue(v, n.name)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(v interface{}) (interface{}, error) {
+	b, err := evalBool(n.operand, v)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *logicalNode) eval(v interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, v)
+	if err != nil {
+		return nil, err
+	}
+	// short circuit
+	if n.op == "&&" && !left {
+		return false, nil
+	}
+	if n.op == "||" && left {
+		return true, nil
+	}
+	return evalBool(n.right, v)
+}
+
+type compareNode struct {
+	op    string
+	left  node
+	right node
+	re    *regexp.Regexp
+}
+
+func (n *compareNode) eval(v interface{}) (interface{}, error) {
+	left, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "=~" {
+		switch l := left.(type) {
+		case string:
+			return n.re.MatchString(l), nil
+		case []string:
+			for _, s := range l {
+				if n.re.MatchString(s) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return nil, fmt.Errorf("=~ needs a string or list on the left, got %T", left)
+	}
+
+	right, err := n.right.eval(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "contains" {
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("contains needs a string on the right, got %T", right)
+		}
+		switch l := left.(type) {
+		case string:
+			return strings.Contains(l, r), nil
+		case []string:
+			for _, s := range l {
+				if s == r {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return nil, fmt.Errorf("contains needs a string or list on the left, got %T", left)
+	}
+
+	c, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	}
+	// booleans are only equal or not
+	if _, ok := left.(bool); ok {
+		return nil, fmt.Errorf("%s can not compare booleans", n.op)
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func evalBool(n node, v interface{}) (bool, error) {
+	result, err := n.eval(v)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %T", result)
+	}
+	return b, nil
+}
+
+// Orders two values of the same type, -1, 0 or 1
+func compare(left interface{}, right interface{}) (int, error) {
+	switch l := left.(type) {
+	case bool:
+		if r, ok := right.(bool); ok {
+			// false sorts before true
+			if l == r {
+				return 0, nil
+			}
+			if !l {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case float64:
+		if r, ok := right.(float64); ok {
+			return compareFloat(l, r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case time.Duration:
+		if r, ok := right.(time.Duration); ok {
+			return compareFloat(float64(l), float64(r)), nil
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return compareFloat(float64(l.UnixNano()), float64(r.UnixNano())), nil
+		}
+	}
+	return 0, fmt.Errorf("can not compare %T with %T", left, right)
+}
+
+func compareFloat(l float64, r float64) int {
+	if l < r {
+		return -1
+	}
+	if l > r {
+		return 1
+	}
+	return 0
+}
+
+// Reads an exported field off a struct, or pointer to one, and normalises
+// it to one of bool, float64, string, []string, time.Duration or time.Time
+func fieldValue(v interface{}, name string) (interface{}, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can not read %s from %T", name, v)
+	}
+	field := rv.FieldByName(name)
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, fmt.Errorf("unknown field %s", name)
+	}
+
+	switch f := field.Interface().(type) {
+	case time.Duration:
+		return f, nil
+	case time.Time:
+		return f, nil
+	case *time.Time:
+		if f == nil {
+			return time.Time{}, nil
+		}
+		return *f, nil
+	case []string:
+		return f, nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		return field.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), nil
+	case reflect.String:
+		return field.String(), nil
+	}
+	return nil, fmt.Errorf("field %s of type %s can not be used in a filter", name, field.Type())
+}
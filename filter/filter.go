@@ -0,0 +1,123 @@
+// Package filter implements the small expression language used to pick which
+// PRs show up in a tab, e.g.
+//
+//	!IsDraft && Labels contains "urgent" && RepoName =~ "api-.*"
+//
+// Identifiers are the exported fields of the value being filtered.
+// Durations can be written as 30m, 12h or 2d.
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type Filter struct {
+	src  string
+	root node
+}
+
+func Parse(src string) (*Filter, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %s", src, err)
+	}
+	return &Filter{src: src, root: root}, nil
+}
+
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Reports whether v passes the filter. v is a struct or pointer to one.
+func (f *Filter) Match(v interface{}) (bool, error) {
+	result, err := evalBool(f.root, v)
+	if err != nil {
+		return false, fmt.Errorf("filter %q: %s", f.src, err)
+	}
+	return result, nil
+}
+
+// Checks every field the filter references exists on v so typos are
+// reported up front instead of silently matching nothing
+func (f *Filter) Validate(v interface{}) error {
+	for _, name := range fieldNames(f.root) {
+		if _, err := fieldValue(v, name); err != nil {
+			return fmt.Errorf("filter %q: %s", f.src, err)
+		}
+	}
+	return nil
+}
+
+func fieldNames(n node) []string {
+	switch n := n.(type) {
+	case *fieldNode:
+		return []string{n.name}
+	case *notNode:
+		return fieldNames(n.operand)
+	case *logicalNode:
+		return append(fieldNames(n.left), fieldNames(n.right)...)
+	case *compareNode:
+		return append(fieldNames(n.left), fieldNames(n.right)...)
+	}
+	return nil
+}
+
+// Sort orders values by a single field. Specs look like "Importance desc"
+// or "UpdatedAt asc", the direction defaults to desc.
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+func ParseSort(spec string) (*Sort, error) {
+	parts := strings.Fields(spec)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, fmt.Errorf("sort %q must be a field name optionally followed by asc or desc", spec)
+	}
+	s := &Sort{Field: parts[0], Descending: true}
+	if len(parts) == 2 {
+		switch strings.ToLower(parts[1]) {
+		case "asc":
+			s.Descending = false
+		case "desc":
+		default:
+			return nil, fmt.Errorf("sort %q direction must be asc or desc", spec)
+		}
+	}
+	return s, nil
+}
+
+func (s *Sort) Validate(v interface{}) error {
+	value, err := fieldValue(v, s.Field)
+	if err != nil {
+		return fmt.Errorf("sort %q: %s", s.Field, err)
+	}
+	if _, err := compare(value, value); err != nil {
+		return fmt.Errorf("sort %q: field can not be ordered", s.Field)
+	}
+	return nil
+}
+
+// Sorts a slice of structs or struct pointers in place. Values that can not
+// be compared keep their relative order.
+func (s *Sort) Apply(slice interface{}) {
+	rv := reflect.ValueOf(slice)
+	sort.SliceStable(slice, func(i, j int) bool {
+		a, errA := fieldValue(rv.Index(i).Interface(), s.Field)
+		b, errB := fieldValue(rv.Index(j).Interface(), s.Field)
+		if errA != nil || errB != nil {
+			return false
+		}
+		c, err := compare(a, b)
+		if err != nil {
+			return false
+		}
+		if s.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
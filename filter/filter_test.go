@@ -0,0 +1,127 @@
+package filter
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testPull struct {
+	Title               string
+	RepoName            string
+	Labels              []string
+	IsDraft             bool
+	IAmAuthor           bool
+	Additions           int
+	Importance          float64
+	TimeSinceLastCommit time.Duration
+	ViewedAt            *time.Time
+}
+
+var _ = Describe("Filter", func() {
+	pr := &testPull{
+		Title:               "Fix the login page",
+		RepoName:            "api-gateway",
+		Labels:              []string{"urgent", "backend"},
+		Additions:           120,
+		Importance:          250.5,
+		TimeSinceLastCommit: time.Hour * 30,
+	}
+
+	match := func(src string) bool {
+		f, err := Parse(src)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := f.Match(pr)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	Describe("Match", func() {
+		It("should evaluate boolean fields and logic", func() {
+			Expect(match("!IsDraft")).To(BeTrue())
+			Expect(match("IsDraft || IAmAuthor")).To(BeFalse())
+			Expect(match("!IsDraft && !IAmAuthor")).To(BeTrue())
+			Expect(match("!(IsDraft || IAmAuthor) && true")).To(BeTrue())
+		})
+
+		It("should bind && tighter than ||", func() {
+			Expect(match("true || false && false")).To(BeTrue())
+			Expect(match("(true || false) && false")).To(BeFalse())
+		})
+
+		It("should compare numbers and strings", func() {
+			Expect(match("Additions > 100 && Additions <= 120")).To(BeTrue())
+			Expect(match("Importance == 250.5")).To(BeTrue())
+			Expect(match(`RepoName != "api-gateway"`)).To(BeFalse())
+		})
+
+		It("should compare durations with duration literals", func() {
+			Expect(match("TimeSinceLastCommit > 1d")).To(BeTrue())
+			Expect(match("TimeSinceLastCommit < 90m")).To(BeFalse())
+		})
+
+		It("should support contains on lists and strings", func() {
+			Expect(match(`Labels contains "urgent"`)).To(BeTrue())
+			Expect(match(`Labels contains "frontend"`)).To(BeFalse())
+			Expect(match(`Title contains "login"`)).To(BeTrue())
+		})
+
+		It("should support regex matches", func() {
+			Expect(match(`RepoName =~ "^api-.*"`)).To(BeTrue())
+			Expect(match(`Labels =~ "^back"`)).To(BeTrue())
+		})
+
+		It("should treat a nil time as the zero time", func() {
+			f, _ := Parse("ViewedAt == ViewedAt")
+			Expect(f.Match(pr)).To(BeTrue())
+		})
+
+		It("should error on mismatched types", func() {
+			f, err := Parse(`Additions == "many"`)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Match(pr)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Parse", func() {
+		It("should reject malformed expressions", func() {
+			for _, src := range []string{"", "IsDraft &&", "(IsDraft", `Title == "open`, "IsDraft IAmAuthor", `RepoName =~ "("`, "Title =~ RepoName", "a # b"} {
+				_, err := Parse(src)
+				Expect(err).To(HaveOccurred(), src)
+			}
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should report unknown fields", func() {
+			f, _ := Parse("IsDraft || IsDrafty")
+			Expect(f.Validate(&testPull{})).To(HaveOccurred())
+		})
+	})
+
+	Describe("Sort", func() {
+		It("should order by a field descending by default", func() {
+			pulls := []*testPull{{Additions: 1}, {Additions: 3}, {Additions: 2}}
+			s, err := ParseSort("Additions")
+			Expect(err).NotTo(HaveOccurred())
+			s.Apply(pulls)
+			Expect([]int{pulls[0].Additions, pulls[1].Additions, pulls[2].Additions}).To(Equal([]int{3, 2, 1}))
+		})
+
+		It("should order ascending", func() {
+			pulls := []*testPull{{Title: "b"}, {Title: "c"}, {Title: "a"}}
+			s, _ := ParseSort("Title asc")
+			s.Apply(pulls)
+			Expect([]string{pulls[0].Title, pulls[1].Title, pulls[2].Title}).To(Equal([]string{"a", "b", "c"}))
+		})
+
+		It("should reject bad specs", func() {
+			_, err := ParseSort("Title sideways")
+			Expect(err).To(HaveOccurred())
+			s, _ := ParseSort("Labels")
+			Expect(s.Validate(&testPull{})).To(HaveOccurred())
+		})
+	})
+})
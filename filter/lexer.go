@@ -0,0 +1,161 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenBool
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+
+	// literal values
+	str      string
+	num      float64
+	duration time.Duration
+	boolean  bool
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!"}
+
+// Duration literals allow days on top of what time.ParseDuration supports
+var durationUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': time.Hour * 24,
+}
+
+func lex(src string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(src) {
+		ch := src[i]
+		switch {
+		case unicode.IsSpace(rune(ch)):
+			i++
+
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case ch == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text := src[i : end+1]
+			str, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s at %d", text, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i, str: str})
+			i = end + 1
+
+		case isDigit(ch):
+			end := i
+			for end < len(src) && (isDigit(src[end]) || src[end] == '.') {
+				end++
+			}
+			num, err := strconv.ParseFloat(src[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s at %d", src[i:end], i)
+			}
+			// a unit directly after the number makes it a duration
+			if end < len(src) && !isIdentChar(next(src, end+1)) {
+				if unit, ok := durationUnits[src[end]]; ok {
+					tokens = append(tokens, token{
+						kind:     tokenDuration,
+						text:     src[i : end+1],
+						pos:      i,
+						duration: time.Duration(num * float64(unit)),
+					})
+					i = end + 1
+					continue
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:end], pos: i, num: num})
+			i = end
+
+		case isIdentStart(ch):
+			end := i
+			for end < len(src) && isIdentChar(src[end]) {
+				end++
+			}
+			text := src[i:end]
+			switch text {
+			case "true", "false":
+				tokens = append(tokens, token{kind: tokenBool, text: text, pos: i, boolean: text == "true"})
+			case "contains":
+				tokens = append(tokens, token{kind: tokenOp, text: text, pos: i})
+			default:
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: i})
+			}
+			i = end
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// The byte at i or 0 past the end
+func next(src string, i int) byte {
+	if i < len(src) {
+		return src[i]
+	}
+	return 0
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentChar(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch)
+}
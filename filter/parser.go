@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Grammar, loosest binding first
+//
+//	expr    := and ( "||" and )*
+//	and     := unary ( "&&" unary )*
+//	unary   := "!" unary | compare
+//	compare := operand ( op operand )?
+//	operand := "(" expr ")" | ident | string | number | duration | bool
+//	op      := "==" | "!=" | "<" | "<=" | ">" | ">=" | "contains" | "=~"
+type node interface {
+	eval(v interface{}) (interface{}, error)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.advance()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	opToken := p.peek()
+	op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=", "contains", "=~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &compareNode{op: op, left: left, right: right}
+	// regexes are compiled once up front so bad patterns fail at parse time
+	if op == "=~" {
+		lit, isLiteral := right.(*literalNode)
+		str, isString := "", false
+		if isLiteral {
+			str, isString = lit.v.(string)
+		}
+		if !isString {
+			return nil, fmt.Errorf("=~ at %d must be followed by a string", opToken.pos)
+		}
+		n.re, err = regexp.Compile(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q at %d: %s", str, opToken.pos, err)
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) at %d", closing.pos)
+		}
+		return n, nil
+	case tokenIdent:
+		return &fieldNode{name: t.text}, nil
+	case tokenString:
+		return &literalNode{v: t.str}, nil
+	case tokenNumber:
+		return &literalNode{v: t.num}, nil
+	case tokenDuration:
+		return &literalNode{v: t.duration}, nil
+	case tokenBool:
+		return &literalNode{v: t.boolean}, nil
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
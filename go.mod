@@ -1,9 +1,10 @@
 module github.com/inburst/prty
 
-go 1.13
+go 1.16
 
 require (
 	github.com/ajones/go-mixpanel v0.0.0-20210424051535-390f42e0fe8b
+	github.com/alecthomas/chroma v0.8.2
 	github.com/charmbracelet/bubbletea v0.13.2
 	github.com/charmbracelet/glamour v0.3.0
 	github.com/charmbracelet/lipgloss v0.1.2
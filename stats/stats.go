@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/inburst/prty/config"
@@ -39,9 +40,13 @@ type Stats struct {
 
 	LifetimePRViews int `yaml:"LifetimePRViews"`
 	LifetimePROpens int `yaml:"LifetimePROpens"`
+	// approvals, change requests and comments posted from prty
+	LifetimePRReviews int `yaml:"LifetimePRReviews"`
 
 	PRViewsPerAuthor map[string]int `yaml:"PRViewsPerAuthor"`
 	PROpensPerAuthor map[string]int `yaml:"PROpensPerAuthor"`
+	// keyed by review event, e.g. APPROVE
+	PRReviewsPerEvent map[string]int `yaml:"PRReviewsPerEvent"`
 }
 
 type TrainingDataV1 struct {
@@ -79,6 +84,9 @@ func LoadStats() (*Stats, error) {
 	if s.PROpensPerAuthor == nil {
 		s.PROpensPerAuthor = make(map[string]int)
 	}
+	if s.PRReviewsPerEvent == nil {
+		s.PRReviewsPerEvent = make(map[string]int)
+	}
 
 	return s, nil
 }
@@ -135,6 +143,16 @@ func (s *Stats) OnOpenPR(pr *datasource.PullRequest) {
 	go AppendEventToTrainingData("open", pr)
 }
 
+func (s *Stats) OnReviewPR(pr *datasource.PullRequest, event string) {
+	// always save after edits
+	defer s.SaveToFile()
+
+	s.LifetimePRReviews += 1
+	s.PRReviewsPerEvent[event] += 1
+
+	go AppendEventToTrainingData("review", pr)
+}
+
 func (s *Stats) SaveToFile() error {
 	statsPath, err := config.GetStatsFilePath()
 	if err != nil {
@@ -150,6 +168,9 @@ func (s *Stats) SaveToFile() error {
 	return err
 }
 
+// views, opens, reviews and skips are appended from their own goroutines
+var trainingDataMutex sync.Mutex
+
 func AppendEventToTrainingData(eventName string, pr *datasource.PullRequest) error {
 	trainingDataPath, err := config.GetTrainingFilePath()
 	if err != nil {
@@ -187,6 +208,8 @@ func AppendEventToTrainingData(eventName string, pr *datasource.PullRequest) err
 	}
 
 	// append to the file
+	trainingDataMutex.Lock()
+	defer trainingDataMutex.Unlock()
 	f, err := os.OpenFile(trainingDataPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
 		logger.Shared().Printf("err opening training data file %s\n", err)
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"time"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/logger"
+)
+
+// Only the PRs ranked just above the opened one were really passed over
+const maxSkippedPerOpen = 10
+
+const trainingIterations = 2000
+const learningRate = 0.1
+const l2Penalty = 0.01
+
+// Records the PRs listed above the one I chose as skipped. These are the
+// negative examples when training.
+func (s *Stats) OnSkipPRs(prs []*datasource.PullRequest) {
+	if len(prs) > maxSkippedPerOpen {
+		prs = prs[len(prs)-maxSkippedPerOpen:]
+	}
+	// AppendEventToTrainingData serialises writes with the other events
+	go func() {
+		for _, pr := range prs {
+			AppendEventToTrainingData("skip", pr)
+		}
+	}()
+}
+
+func (e PRStatsV1) modelFeatures() datasource.ModelFeatures {
+	return datasource.ModelFeatures{
+		IAmAuthor:                  e.IAmAuthor,
+		AuthorIsTeammate:           e.AuthorIsTeammate,
+		AuthorIsBot:                e.AuthorIsBot,
+		HasChangesAfterLastComment: e.HasChangesAfterLastComment,
+		HasCommentsFromMe:          e.HasCommentsFromMe,
+		LastCommentFromMe:          e.LastCommentFromMe,
+		IsDraft:                    e.IsDraft,
+		CodeDelta:                  e.CodeDelta,
+		TimeSinceLastComment:       e.TimeSinceLastComment,
+		TimeSinceLastCommit:        e.TimeSinceLastCommit,
+		TimeSinceFirstCommit:       e.TimeSinceFirstCommit,
+	}
+}
+
+func loadTrainingEvents() ([]PRStatsV1, error) {
+	trainingDataPath, err := config.GetTrainingFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(trainingDataPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events := []PRStatsV1{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := PRStatsV1{}
+		// a partially written line should not throw away the whole history
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			logger.Shared().Printf("skipping bad training data line %s\n", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Fits a logistic regression on views and opens against skipped PRs
+func Train() (*datasource.Model, error) {
+	events, err := loadTrainingEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	x := [][]float64{}
+	y := []float64{}
+	positives := 0
+	for _, e := range events {
+		switch e.Event {
+		case "view", "open", "review":
+			y = append(y, 1)
+			positives++
+		case "skip":
+			y = append(y, 0)
+		default:
+			continue
+		}
+		x = append(x, e.modelFeatures().Vector())
+	}
+	if positives == 0 || positives == len(y) {
+		return nil, errors.New("not enough history to train on yet, keep using prty and opening PRs from the list")
+	}
+
+	weights, bias := fitLogisticRegression(x, y)
+	return &datasource.Model{
+		Version:   datasource.ModelVersion,
+		TrainedAt: time.Now(),
+		Samples:   len(y),
+		Features:  datasource.ModelFeatureNames,
+		Weights:   weights,
+		Bias:      bias,
+	}, nil
+}
+
+// Batch gradient descent on the L2 regularised log loss
+func fitLogisticRegression(x [][]float64, y []float64) ([]float64, float64) {
+	n := float64(len(x))
+	weights := make([]float64, len(x[0]))
+	bias := 0.0
+	for iter := 0; iter < trainingIterations; iter++ {
+		gradW := make([]float64, len(weights))
+		gradB := 0.0
+		for i := range x {
+			z := bias
+			for j := range weights {
+				z += weights[j] * x[i][j]
+			}
+			diff := sigmoid(z) - y[i]
+			for j := range weights {
+				gradW[j] += diff * x[i][j]
+			}
+			gradB += diff
+		}
+		for j := range weights {
+			weights[j] -= learningRate * (gradW[j]/n + l2Penalty*weights[j])
+		}
+		bias -= learningRate * gradB / n
+	}
+	return weights, bias
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
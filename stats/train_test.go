@@ -0,0 +1,23 @@
+package stats
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fitLogisticRegression", func() {
+	It("should learn a positive weight for a feature that predicts opens", func() {
+		x := [][]float64{}
+		y := []float64{}
+		for i := 0; i < 20; i++ {
+			x = append(x, []float64{1, 0})
+			y = append(y, 1)
+			x = append(x, []float64{0, 1})
+			y = append(y, 0)
+		}
+
+		weights, _ := fitLogisticRegression(x, y)
+		Expect(weights[0]).To(BeNumerically(">", 0))
+		Expect(weights[1]).To(BeNumerically("<", 0))
+	})
+})
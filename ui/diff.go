@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(blue)
+)
+
+// renderedDiff is a file's patch highlighted once up front so scrolling only
+// has to slice it
+type renderedDiff struct {
+	lines []string
+	// index into lines of each "@@" hunk header
+	hunks []int
+}
+
+// Highlights the code on each line with the lexer for the file type and
+// marks added and removed lines in the gutter. Lines are highlighted one at
+// a time because a hunk rarely holds enough context to lex correctly anyway.
+func renderDiff(path string, patch string) *renderedDiff {
+	d := &renderedDiff{}
+	if len(patch) == 0 {
+		d.lines = []string{"No diff available, the file may be binary or too large"}
+		return d
+	}
+
+	lexer := lexers.Match(path)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	formatter := formatters.Get("terminal256")
+	style := styles.Get("monokai")
+
+	highlight := func(code string) string {
+		iterator, err := lexer.Tokenise(nil, code)
+		if err != nil {
+			return code
+		}
+		out := strings.Builder{}
+		if err := formatter.Format(&out, style, iterator); err != nil {
+			return code
+		}
+		// lexers always end the input with a newline, drop it
+		return strings.ReplaceAll(out.String(), "\n", "")
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if strings.HasPrefix(line, "@@") {
+			d.hunks = append(d.hunks, len(d.lines))
+			d.lines = append(d.lines, diffHunkStyle.Render(line))
+			continue
+		}
+
+		gutter, code := " ", line
+		if len(line) > 0 {
+			gutter, code = line[:1], line[1:]
+		}
+		switch gutter {
+		case "+":
+			gutter = diffAddedStyle.Render("+")
+		case "-":
+			gutter = diffRemovedStyle.Render("-")
+		case "\\":
+			// "\ No newline at end of file"
+			d.lines = append(d.lines, line)
+			continue
+		}
+		d.lines = append(d.lines, gutter+highlight(code))
+	}
+	return d
+}
+
+// The first hunk after line or -1
+func (d *renderedDiff) nextHunk(line int) int {
+	for _, h := range d.hunks {
+		if h > line {
+			return h
+		}
+	}
+	return -1
+}
+
+// The last hunk before line or -1
+func (d *renderedDiff) previousHunk(line int) int {
+	for i := len(d.hunks) - 1; i >= 0; i-- {
+		if d.hunks[i] < line {
+			return d.hunks[i]
+		}
+	}
+	return -1
+}
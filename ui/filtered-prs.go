@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cznic/mathutil"
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/filter"
+	"github.com/inburst/prty/logger"
+	"github.com/inburst/prty/stats"
+	"github.com/inburst/prty/tracking"
+)
+
+// metric names the default tabs have always reported
+var tabMetrics = map[string]string{
+	"Needs Attention": "open.priority",
+	"Requested":       "open.requested",
+	"Team":            "open.team",
+	"Mine":            "open.my",
+	"Active":          "open.active",
+	"Bots":            "open.bots",
+	"All":             "open.all",
+}
+
+// FilteredPRView shows the PRs matching a filter expression
+type FilteredPRView struct {
+	PRView
+
+	name   string
+	filter *filter.Filter
+	sorter *filter.Sort
+}
+
+func NewFilteredPRView(tab config.TabConfig) (*FilteredPRView, error) {
+	f, sorter, err := datasource.ParseTab(tab)
+	if err != nil {
+		return nil, err
+	}
+	return &FilteredPRView{
+		name:   tab.Name,
+		filter: f,
+		sorter: sorter,
+	}, nil
+}
+
+func BuildTabs(c *config.Config) ([]string, []PRViewData, error) {
+	names := []string{}
+	views := []PRViewData{}
+	for _, tab := range datasource.TabConfigs(c) {
+		v, err := NewFilteredPRView(tab)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tab [%s]: %s", tab.Name, err)
+		}
+		names = append(names, tab.Name)
+		views = append(views, v)
+	}
+	return names, views, nil
+}
+
+func (p *FilteredPRView) Name() string {
+	return p.name
+}
+
+func (p *FilteredPRView) OnNewPullData(pr *datasource.PullRequest) {
+	matches, err := p.filter.Match(pr)
+	if err != nil {
+		logger.Shared().Printf("tab [%s] %s\n", p.name, err)
+	}
+	p.upsert(pr, matches)
+}
+
+func (p *FilteredPRView) OnSort() {
+	p.sorter.Apply(p.pulls)
+	p.needsSort = false
+}
+
+func (p *FilteredPRView) NeedsSort() bool {
+	return p.needsSort
+}
+
+func (p *FilteredPRView) OnSelect(cursor CursorPos, stats *stats.Stats) {
+	pull := p.GetSelectedPull()
+	if pull == nil {
+		return
+	}
+
+	now := time.Now()
+	pull.ViewedAt = &now
+
+	openbrowser(pull.URL)
+	stats.OnOpenPR(pull)
+	stats.OnSkipPRs(p.pulls[:p.currentlySelectedPullIndex])
+
+	metric, ok := tabMetrics[p.name]
+	if !ok {
+		metric = "open.custom"
+	}
+	tracking.SendMetric(metric)
+}
+
+func (p *FilteredPRView) Clear() {
+	p.pulls = []*datasource.PullRequest{}
+	p.currentlySelectedPullIndex = 0
+}
+
+func (p *FilteredPRView) OnCursorMove(moxedX int, movedY int) bool {
+	if movedY != 0 {
+		p.cursor.Y += movedY
+
+		p.cursor.Y = mathutil.Clamp(p.cursor.Y, 0, max(len(p.pulls)-1, 0))
+		p.currentlySelectedPullIndex = p.cursor.Y
+		return true
+	}
+	return false
+}
+
+func (p *FilteredPRView) GetSelectedIndex() int {
+	return p.currentlySelectedPullIndex
+}
+
+func (p *FilteredPRView) GetPulls() []*datasource.PullRequest {
+	return p.pulls
+}
+
+// nil when the tab is empty
+func (p *FilteredPRView) GetSelectedPull() *datasource.PullRequest {
+	if p.currentlySelectedPullIndex < 0 || p.currentlySelectedPullIndex >= len(p.pulls) {
+		return nil
+	}
+	return p.pulls[p.currentlySelectedPullIndex]
+}
@@ -32,6 +32,30 @@ type PRView struct {
 	cursor                     CursorPos
 }
 
+// Adds, replaces or removes the PR depending on whether it still belongs in
+// this view. PRs are matched by ID so a refresh updates rows in place.
+func (p *PRView) upsert(pr *datasource.PullRequest, belongs bool) {
+	belongs = belongs && !pr.IsClosed
+	for i := range p.pulls {
+		if p.pulls[i].ID != pr.ID {
+			continue
+		}
+		if belongs {
+			p.pulls[i] = pr
+		} else {
+			p.pulls = append(p.pulls[:i], p.pulls[i+1:]...)
+			p.cursor.Y = min(p.cursor.Y, max(len(p.pulls)-1, 0))
+			p.currentlySelectedPullIndex = p.cursor.Y
+		}
+		p.needsSort = true
+		return
+	}
+	if belongs {
+		p.pulls = append(p.pulls, pr)
+		p.needsSort = true
+	}
+}
+
 func BuildHeader(viewWidth int, viewHeight int) string {
 	w := lipgloss.Width
 	doc := strings.Builder{}
@@ -49,7 +73,7 @@ func BuildHeader(viewWidth int, viewHeight int) string {
 	}
 	renderedTitle := lipgloss.NewStyle().Padding(0, 2).Render(title.String())
 
-	shortcuts := list.Copy().Width(40).Padding(0, 2).Render(
+	shortcuts := list.Copy().Width(56).Padding(0, 2).Render(
 		lipgloss.JoinVertical(lipgloss.Center,
 			listHeader("Keyboard Shortcuts"),
 			lipgloss.JoinHorizontal(lipgloss.Top,
@@ -65,6 +89,12 @@ func BuildHeader(viewWidth int, viewHeight int) string {
 					listItem("[esc] back"),
 					listItem("[hjkl] vim move"),
 				),
+				lipgloss.JoinVertical(lipgloss.Left,
+					listItem("[a]pprove"),
+					listItem("[x] changes"),
+					listItem("[c]omment"),
+					listItem("[w]orktree"),
+				),
 			),
 		),
 	)
@@ -86,7 +116,7 @@ func BuildHeader(viewWidth int, viewHeight int) string {
 		Render(doc.String()) + "\n"
 }
 
-func BuildPRView(p PRViewData, viewWidth int, viewHeight int, isRefreshing bool) string {
+func BuildPRView(p PRViewData, viewWidth int, viewHeight int, ds *datasource.Datasource) string {
 	doc := strings.Builder{}
 	pullPosHeight := 1
 	bodyHeight := viewHeight - pullPosHeight
@@ -113,18 +143,18 @@ func BuildPRView(p PRViewData, viewWidth int, viewHeight int, isRefreshing bool)
 
 			if i == 0 {
 				prSection.WriteString(
-					pullListStyleSelected.Copy().Width(viewWidth).Render(fmt.Sprintf(">>> %s", pr.PR.GetTitle())))
+					pullListStyleSelected.Copy().Width(viewWidth).Render(fmt.Sprintf(">>> %s", pr.Title)))
 			} else {
 				prSection.WriteString(
-					pullListStyle.Copy().Width(viewWidth).Render(*pr.PR.Title))
+					pullListStyle.Copy().Width(viewWidth).Render(pr.Title))
 
 			}
 			prSection.WriteString("\n")
-			prSection.WriteString(BuildPRFooter(p, viewWidth, pr))
+			prSection.WriteString(BuildPRFooter(p, viewWidth, pr, ds))
 			prSection.WriteString("\n")
 		}
 	} else {
-		if isRefreshing {
+		if ds.IsCurrentlyRefreshingData() {
 			prSection.WriteString(lipgloss.NewStyle().Width(viewWidth).Align(lipgloss.Center).Render("refreshing..."))
 		} else {
 			prSection.WriteString(lipgloss.NewStyle().Width(viewWidth).Align(lipgloss.Center).Render("nothing to show\nhere is a cat 🐈\n\n[r]eload"))
@@ -140,28 +170,16 @@ func BuildPRView(p PRViewData, viewWidth int, viewHeight int, isRefreshing bool)
 	return lipgloss.NewStyle().MaxWidth(viewWidth).Render(doc.String()) + "\n"
 }
 
-func BuildPRFooter(p PRViewData, viewWidth int, pr *datasource.PullRequest) string {
+func BuildPRFooter(p PRViewData, viewWidth int, pr *datasource.PullRequest, ds *datasource.Datasource) string {
 	foot := strings.Builder{}
 
 	w := lipgloss.Width
 
-	var statusTag string
-	if pr.IsApproved {
-		statusTag = prTagLeftStyle.Copy().Inherit(tagSuccessStyle).Render("APPROVED")
-	} else if pr.IsAbandoned {
-		statusTag = prTagLeftStyle.Copy().Inherit(tagStyle).Background(darkerGrey).Render("ABANDONED 💀")
-	} else if pr.IsDraft {
-		statusTag = prTagLeftStyle.Copy().Inherit(tagStyle).Render("DRAFT")
-	} else if pr.HasChangesAfterLastComment {
-		statusTag = prTagLeftStyle.Copy().Inherit(tagAlertStyle).Render("NEEDS REVIEW")
-	} else {
-		statusTag = prTagLeftStyle.Copy().Inherit(tagStyle).Render("OK")
-	}
-
-	viewedIcon := ""
-	if pr.ViewedAt != nil {
-		viewedIcon = prTagLeftStyle.Copy().Inherit(tagSuccessStyle).Render("OPENED")
-	}
+	statusTag := renderTag(pr.StatusTag())
+	viewedIcon := renderTag(pr.OpenedTag())
+	ownerTag := renderTag(pr.OwnerTag())
+	approvalsTag := renderTag(pr.ApprovalsTag())
+	checksTag := renderTag(pr.ChecksTag()) + renderTag(pr.ConflictsTag())
 
 	age := time.Now().Sub(pr.FirstCommitTime)
 	commitsCountTag := prTagLeftStyle.Copy().Render(fmt.Sprintf("Commits: %d", pr.NumCommits))
@@ -170,11 +188,13 @@ func BuildPRFooter(p PRViewData, viewWidth int, pr *datasource.PullRequest) stri
 		Width(viewWidth - 2 - w(commitsCountTag) - w(ageTag)).
 		Render(fmt.Sprintf("%s/%s", pr.OrgName, pr.RepoName))
 
-	wait := time.Now().Sub(pr.LastCommitTime) // TODO : should be pr open time
+	// working hours since the last commit when a calendar is configured,
+	// measured now like the age rather than when the PR was fetched
+	wait := ds.BusinessTimeSince(pr.LastCommitTime)
 	waitTag := prTagLeftStyle.Copy().Render(fmt.Sprintf("Wait %sh", formatDurationDayHour(wait)))
 	authorTag := prTagRightStyle.Copy().Render(pr.Author)
 	beenViewedTag := prTagLeftStyle.Copy().
-		Width(viewWidth - 2 - w(statusTag) - w(waitTag) - w(authorTag)).
+		Width(viewWidth - 2 - w(statusTag) - w(waitTag) - w(ownerTag) - w(approvalsTag) - w(checksTag) - w(authorTag)).
 		Render(viewedIcon)
 
 	/*
@@ -185,11 +205,14 @@ func BuildPRFooter(p PRViewData, viewWidth int, pr *datasource.PullRequest) stri
 		- PR age
 		- author
 		- wait
+		- owner
+		- approvals
+		- checks / conflicts
 		- viewed
 
 		layout:
 		num commits   | org/repo name         ----  age
-		status        | wait       | viewed ---- author
+		status        | wait | owner | approvals | checks | viewed ---- author
 	*/
 
 	topBar := lipgloss.JoinHorizontal(lipgloss.Top,
@@ -201,6 +224,9 @@ func BuildPRFooter(p PRViewData, viewWidth int, pr *datasource.PullRequest) stri
 	bottomBar := lipgloss.JoinHorizontal(lipgloss.Top,
 		statusTag,
 		waitTag,
+		ownerTag,
+		approvalsTag,
+		checksTag,
 		beenViewedTag,
 		authorTag,
 	)
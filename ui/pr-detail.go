@@ -10,8 +10,30 @@ import (
 	"github.com/inburst/prty/datasource"
 )
 
+const (
+	detailTabOverview = iota
+	detailTabFiles
+	detailTabDiff
+	detailTabThreads
+)
+
+var detailTabNames = []string{"Overview", "Files", "Diff", "Threads"}
+
 type PRDetail struct {
 	PR *datasource.PullRequest
+	// fetches the changed files the first time the files or diff tab is shown
+	LoadFiles func() ([]*datasource.FileDiff, error)
+
+	nav          TabNav
+	tab          int
+	files        []*datasource.FileDiff
+	filesErr     error
+	loadingFiles bool
+	selectedFile int
+	// diffs are highlighted on first view, keyed by file index
+	diffs         map[int]*renderedDiff
+	diffScroll    int
+	threadsScroll int
 }
 
 type importanceEntry struct {
@@ -41,7 +63,7 @@ func (p *PRDetail) BuildView(viewWidth int, viewHeight int) string {
 	deletionsWidth := len(deletionsStr)
 	codeDeltaTotalWidth := additionsWidth + deletionsWidth + 4 // +4 for the padding between blocks
 
-	prTitleBlock := prTitleStyle.Copy().Inherit(titleStyle).Width(viewWidth - codeDeltaTotalWidth).Render(p.PR.PR.GetTitle())
+	prTitleBlock := prTitleStyle.Copy().Inherit(titleStyle).Width(viewWidth - codeDeltaTotalWidth).Render(p.PR.Title)
 	additionsBlock := prAdditionsAndDeletionsStyle.Copy().Foreground(lipgloss.Color("#00FF00")).Width(additionsWidth).Render(additionsStr)
 	deletionsBlock := prAdditionsAndDeletionsStyle.Copy().Foreground(lipgloss.Color("#FF0000")).Width(deletionsWidth).Render(deletionsStr)
 
@@ -53,6 +75,26 @@ func (p *PRDetail) BuildView(viewWidth int, viewHeight int) string {
 	doc.WriteString(titleBar + "\n")
 	// End Title Bar
 
+	navHeight := 3
+	doc.WriteString(p.nav.BuildView(viewWidth, navHeight, detailTabNames, p.tab))
+	bodyHeight := viewHeight - h(titleBar) - navHeight
+
+	switch p.tab {
+	case detailTabFiles:
+		doc.WriteString(p.buildFiles(viewWidth, bodyHeight))
+	case detailTabDiff:
+		doc.WriteString(p.buildDiff(viewWidth, bodyHeight))
+	case detailTabThreads:
+		doc.WriteString(p.buildThreads(viewWidth, bodyHeight))
+	default:
+		doc.WriteString(p.buildOverview(viewWidth, bodyHeight))
+	}
+
+	return lipgloss.NewStyle().MaxWidth(viewWidth).Render(doc.String()) + "\n"
+}
+
+// The PR body alongside the importance breakdown
+func (p *PRDetail) buildOverview(viewWidth int, viewHeight int) string {
 	// Begin PR Markdown Body
 	r, _ := glamour.NewTermRenderer(
 		// detect background color and pick either the default dark or light theme
@@ -60,7 +102,7 @@ func (p *PRDetail) BuildView(viewWidth int, viewHeight int) string {
 		// wrap output at specific width
 		//glamour.WithWordWrap(viewWidth-20),
 	)
-	markdownBody, _ := r.Render(p.PR.PR.GetBody())
+	markdownBody, _ := r.Render(p.PR.Body)
 	// End PR Markdown Body
 
 	// Begin Importance Display
@@ -103,14 +145,14 @@ func (p *PRDetail) BuildView(viewWidth int, viewHeight int) string {
 	markDownBodyBlock := lipgloss.NewStyle().
 		Width(viewWidth-importanceBreakdownWidth).
 		MaxWidth(viewWidth-importanceBreakdownWidth).
-		Height(viewHeight-h(titleBar)).
+		Height(viewHeight).
 		Render(markdownBody) + "\n"
 
 	importanceBodyBlock := lipgloss.NewStyle().
 		Padding(1, 1, 1, 1).
 		Width(importanceBreakdownWidth).
 		MaxWidth(importanceBreakdownWidth).
-		Height(viewHeight-h(titleBar)).
+		Height(viewHeight).
 		Render(importanceList) + "\n"
 
 	bodyBlock := lipgloss.NewStyle().Copy().Render(
@@ -119,12 +161,91 @@ func (p *PRDetail) BuildView(viewWidth int, viewHeight int) string {
 			importanceBodyBlock,
 		))
 
-	renderedBody := lipgloss.NewStyle().
-		MaxHeight(viewHeight - h(titleBar)).
-		Height(viewHeight - h(titleBar)).
+	return lipgloss.NewStyle().
+		MaxHeight(viewHeight).
+		Height(viewHeight).
 		Render(bodyBlock)
+}
 
-	doc.WriteString(renderedBody)
+// Handles keys for the detail screen's tabs. Returns false for keys it does
+// not use so they fall through to the global bindings.
+func (p *PRDetail) OnKey(key string) bool {
+	switch key {
+	case "tab":
+		p.showTab((p.tab + 1) % len(detailTabNames))
+		return true
+	case "shift+tab":
+		p.showTab((p.tab + len(detailTabNames) - 1) % len(detailTabNames))
+		return true
+	}
 
-	return lipgloss.NewStyle().MaxWidth(viewWidth).Render(doc.String()) + "\n"
+	switch p.tab {
+	case detailTabFiles:
+		switch key {
+		case "down", "j":
+			p.selectFile(p.selectedFile + 1)
+		case "up", "k":
+			p.selectFile(p.selectedFile - 1)
+		case "enter":
+			p.showTab(detailTabDiff)
+		default:
+			return false
+		}
+		return true
+
+	case detailTabDiff:
+		diff := p.currentDiff()
+		switch key {
+		case "down", "j":
+			p.scrollDiff(1)
+		case "up", "k":
+			p.scrollDiff(-1)
+		case "pgdown":
+			p.scrollDiff(20)
+		case "pgup":
+			p.scrollDiff(-20)
+		case "n":
+			p.selectFile(p.selectedFile + 1)
+		case "p":
+			p.selectFile(p.selectedFile - 1)
+		case "]":
+			if diff != nil {
+				if next := diff.nextHunk(p.diffScroll); next >= 0 {
+					p.diffScroll = next
+				}
+			}
+		case "[":
+			if diff != nil {
+				if previous := diff.previousHunk(p.diffScroll); previous >= 0 {
+					p.diffScroll = previous
+				}
+			}
+		default:
+			return false
+		}
+		return true
+
+	case detailTabThreads:
+		switch key {
+		case "down", "j":
+			p.threadsScroll++
+		case "up", "k":
+			p.threadsScroll = max(p.threadsScroll-1, 0)
+		case "pgdown":
+			p.threadsScroll += 20
+		case "pgup":
+			p.threadsScroll = max(p.threadsScroll-20, 0)
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (p *PRDetail) showTab(tab int) {
+	p.tab = tab
+	if tab == detailTabFiles || tab == detailTabDiff {
+		p.loadFiles()
+	}
 }
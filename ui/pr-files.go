@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/inburst/prty/datasource"
+)
+
+var fileStatusIcons = map[string]string{
+	"added":    diffAddedStyle.Render("A"),
+	"removed":  diffRemovedStyle.Render("D"),
+	"renamed":  diffHunkStyle.Render("R"),
+	"modified": "M",
+}
+
+// Fetches the changed files in the background the first time they are needed
+func (p *PRDetail) loadFiles() {
+	if p.files != nil || p.loadingFiles || p.LoadFiles == nil {
+		return
+	}
+	p.loadingFiles = true
+	go func() {
+		files, err := p.LoadFiles()
+		if files == nil {
+			files = []*datasource.FileDiff{}
+		}
+		p.diffs = map[int]*renderedDiff{}
+		p.files, p.filesErr = files, err
+		p.loadingFiles = false
+	}()
+}
+
+func (p *PRDetail) selectFile(i int) {
+	if i < 0 || i >= len(p.files) {
+		return
+	}
+	p.selectedFile = i
+	p.diffScroll = 0
+}
+
+func (p *PRDetail) currentDiff() *renderedDiff {
+	if p.loadingFiles || p.selectedFile >= len(p.files) {
+		return nil
+	}
+	diff, ok := p.diffs[p.selectedFile]
+	if !ok {
+		f := p.files[p.selectedFile]
+		diff = renderDiff(f.Path, f.Patch)
+		p.diffs[p.selectedFile] = diff
+	}
+	return diff
+}
+
+func (p *PRDetail) scrollDiff(lines int) {
+	diff := p.currentDiff()
+	if diff == nil {
+		return
+	}
+	p.diffScroll = min(max(p.diffScroll+lines, 0), max(len(diff.lines)-1, 0))
+}
+
+// Explains why there is nothing to show yet or returns ""
+func (p *PRDetail) filesStatus() string {
+	switch {
+	case p.loadingFiles || p.files == nil:
+		return "loading files..."
+	case p.filesErr != nil:
+		return fmt.Sprintf("ERROR: %s", p.filesErr)
+	case len(p.files) == 0:
+		return "no files changed"
+	}
+	return ""
+}
+
+func (p *PRDetail) buildFiles(viewWidth int, viewHeight int) string {
+	body := lipgloss.NewStyle().Width(viewWidth).Height(viewHeight).MaxHeight(viewHeight)
+	if status := p.filesStatus(); len(status) > 0 {
+		return body.Render(detailSideBarStyle.Render(status))
+	}
+
+	// keep the selected file on screen
+	rows := viewHeight - 1
+	first := max(0, p.selectedFile-rows+1)
+
+	lines := []string{}
+	for i := first; i < len(p.files) && i < first+rows; i++ {
+		f := p.files[i]
+		path := f.Path
+		if len(f.PreviousPath) > 0 {
+			path = fmt.Sprintf("%s → %s", f.PreviousPath, f.Path)
+		}
+		icon, ok := fileStatusIcons[f.Status]
+		if !ok {
+			icon = "M"
+		}
+		counts := diffAddedStyle.Render(fmt.Sprintf("+%d", f.Additions)) + " " +
+			diffRemovedStyle.Render(fmt.Sprintf("-%d", f.Deletions))
+
+		row := lipgloss.NewStyle().Padding(0, 1)
+		if i == p.selectedFile {
+			row = row.Background(purple).Foreground(white)
+		}
+		lines = append(lines, row.Width(viewWidth).Render(fmt.Sprintf("%s %s  %s", icon, path, counts)))
+	}
+	lines = append(lines, detailSideBarStyle.Render("j/k select • enter view diff • tab switch"))
+
+	return body.Render(strings.Join(lines, "\n"))
+}
+
+func (p *PRDetail) buildDiff(viewWidth int, viewHeight int) string {
+	body := lipgloss.NewStyle().Width(viewWidth).Height(viewHeight).MaxHeight(viewHeight).MaxWidth(viewWidth)
+	if status := p.filesStatus(); len(status) > 0 {
+		return body.Render(detailSideBarStyle.Render(status))
+	}
+
+	f := p.files[p.selectedFile]
+	diff := p.currentDiff()
+	header := prTitleStyle.Copy().Padding(0, 1).Width(viewWidth).Render(
+		fmt.Sprintf("%s (%d/%d)  %s %s", f.Path, p.selectedFile+1, len(p.files),
+			diffAddedStyle.Render(fmt.Sprintf("+%d", f.Additions)),
+			diffRemovedStyle.Render(fmt.Sprintf("-%d", f.Deletions))))
+	help := detailSideBarStyle.Render("j/k scroll • n/p file • ]/[ hunk • tab switch")
+
+	rows := viewHeight - 2
+	end := min(len(diff.lines), p.diffScroll+rows)
+	lines := diff.lines[min(p.diffScroll, end):end]
+
+	return body.Render(lipgloss.JoinVertical(lipgloss.Left,
+		header,
+		lipgloss.NewStyle().Height(rows).MaxHeight(rows).Render(strings.Join(lines, "\n")),
+		help,
+	))
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/inburst/prty/datasource"
+)
+
+// Lines of the diff hunk shown above each thread, the commented line is last
+const threadContextLines = 4
+
+var threadAuthorStyle = lipgloss.NewStyle().Bold(true).Foreground(purple)
+
+func (p *PRDetail) buildThreads(viewWidth int, viewHeight int) string {
+	body := lipgloss.NewStyle().Width(viewWidth).Height(viewHeight).MaxHeight(viewHeight).MaxWidth(viewWidth)
+	if len(p.PR.Threads) == 0 {
+		return body.Render(detailSideBarStyle.Render("no review comments"))
+	}
+
+	lines := []string{}
+	for _, t := range p.PR.Threads {
+		lines = append(lines, renderThread(t, viewWidth)...)
+		lines = append(lines, "")
+	}
+
+	rows := viewHeight - 1
+	p.threadsScroll = min(p.threadsScroll, max(len(lines)-rows, 0))
+	end := min(len(lines), p.threadsScroll+rows)
+	help := detailSideBarStyle.Render("j/k scroll • tab switch")
+
+	return body.Render(lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Height(rows).MaxHeight(rows).Render(strings.Join(lines[p.threadsScroll:end], "\n")),
+		help,
+	))
+}
+
+// A header with the file, line and state, the end of the diff hunk the
+// thread is on and then each comment. Unresolved threads where someone else
+// had the last word are flagged as waiting on me.
+func renderThread(t *datasource.Thread, viewWidth int) []string {
+	tags := ""
+	if t.IsResolved {
+		tags += tagSuccessStyle.Render("RESOLVED")
+	}
+	if t.IsOutdated {
+		tags += tagStyle.Copy().Background(darkerGrey).Render("OUTDATED")
+	}
+	if !t.IsResolved && !t.LastReplyFromMe {
+		tags += tagAlertStyle.Render("AWAITING REPLY")
+	}
+	location := t.Path
+	if t.Line > 0 {
+		location = fmt.Sprintf("%s:%d", t.Path, t.Line)
+	}
+	lines := []string{prTitleStyle.Copy().Padding(0, 1).Render(location) + " " + tags}
+
+	if len(t.DiffHunk) > 0 {
+		lines = append(lines, renderDiff(t.Path, hunkTail(t.DiffHunk)).lines...)
+	}
+
+	text := lipgloss.NewStyle().PaddingLeft(2).Width(viewWidth - 2)
+	for i, c := range t.Comments {
+		indent := 0
+		if i > 0 {
+			indent = 2
+		}
+		age := fmt.Sprintf("%sh ago", formatDurationDayHour(time.Since(c.CreatedAt)))
+		lines = append(lines, text.Copy().PaddingLeft(2+indent).Render(threadAuthorStyle.Render(c.Author)+" "+age))
+		lines = append(lines, strings.Split(text.Copy().PaddingLeft(2+indent).Render(c.Body), "\n")...)
+	}
+	return lines
+}
+
+// Keeps the hunk header and the last few lines, which end at the comment
+func hunkTail(hunk string) string {
+	lines := strings.Split(strings.TrimRight(hunk, "\n"), "\n")
+	if len(lines) <= threadContextLines+1 {
+		return hunk
+	}
+	return strings.Join(append(lines[:1], lines[len(lines)-threadContextLines:]...), "\n")
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/inburst/prty/datasource"
+)
+
+// ReviewInput is the modal for writing the body of an approval, change
+// request or comment before it is posted
+type ReviewInput struct {
+	PR    *datasource.PullRequest
+	Event string
+
+	value []rune
+}
+
+var reviewEventTitles = map[string]string{
+	datasource.ReviewEventApprove:        "Approve",
+	datasource.ReviewEventRequestChanges: "Request changes on",
+	datasource.ReviewEventComment:        "Comment on",
+}
+
+func (r *ReviewInput) Insert(runes []rune) {
+	r.value = append(r.value, runes...)
+}
+
+func (r *ReviewInput) Backspace() {
+	if len(r.value) > 0 {
+		r.value = r.value[:len(r.value)-1]
+	}
+}
+
+func (r *ReviewInput) Value() string {
+	return strings.TrimSpace(string(r.value))
+}
+
+// Approvals can be empty, change requests and comments need a body
+func (r *ReviewInput) CanSubmit() bool {
+	return r.Event == datasource.ReviewEventApprove || len(r.Value()) > 0
+}
+
+func (r *ReviewInput) BuildView(viewWidth int, viewHeight int) string {
+	doc := strings.Builder{}
+
+	title := fmt.Sprintf("%s %s/%s #%d", reviewEventTitles[r.Event], r.PR.OrgName, r.PR.RepoName, r.PR.Number)
+	doc.WriteString(prTitleStyle.Copy().Inherit(titleStyle).Width(viewWidth).Render(title) + "\n")
+	doc.WriteString(prTitleStyle.Copy().Width(viewWidth).Render(r.PR.Title) + "\n")
+
+	input := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(purple).
+		Padding(0, 1).
+		Width(viewWidth - 4).
+		Render(string(r.value) + "█")
+	doc.WriteString(input + "\n")
+
+	help := "enter to submit • esc to cancel"
+	if !r.CanSubmit() {
+		help = "a message is required • esc to cancel"
+	}
+	doc.WriteString(detailSideBarStyle.Copy().Render(help))
+
+	return lipgloss.NewStyle().MaxWidth(viewWidth).Height(viewHeight).Render(doc.String()) + "\n"
+}
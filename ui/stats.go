@@ -25,10 +25,12 @@ func (p *Stats) BuildView(viewWidth int, viewHeight int) string {
 			lipgloss.JoinHorizontal(lipgloss.Top,
 				lipgloss.JoinVertical(lipgloss.Right,
 					statItem.Render("Lifetime Opens"),
+					statItem.Render("Lifetime Reviews"),
 					statItem.Render("\n"),
 				),
 				lipgloss.JoinVertical(lipgloss.Right,
 					statItem.Render(fmt.Sprintf("%d", p.UserStats.LifetimePROpens)),
+					statItem.Render(fmt.Sprintf("%d", p.UserStats.LifetimePRReviews)),
 					statItem.Render("\n"),
 				),
 			),
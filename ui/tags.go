@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"github.com/inburst/prty/datasource"
+)
+
+func renderTag(t datasource.Tag) string {
+	if len(t.Label) == 0 {
+		return ""
+	}
+	style := prTagLeftStyle.Copy()
+	switch t.Kind {
+	case datasource.TagDefault:
+		style = style.Inherit(tagStyle)
+	case datasource.TagMuted:
+		style = style.Inherit(tagStyle).Background(darkerGrey)
+	case datasource.TagAlert:
+		style = style.Inherit(tagAlertStyle)
+	case datasource.TagSuccess:
+		style = style.Inherit(tagSuccessStyle)
+	case datasource.TagPurple:
+		style = style.Inherit(tagPurpleStyle)
+	}
+	return style.Render(t.Label)
+}
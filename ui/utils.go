@@ -8,22 +8,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
-
-	"github.com/inburst/prty/datasource"
 )
 
-type byImportance []*datasource.PullRequest
-
-func (s byImportance) Len() int {
-	return len(s)
-}
-func (s byImportance) Swap(i, j int) {
-	s[i], s[j] = s[j], s[i]
-}
-func (s byImportance) Less(i, j int) bool {
-	return s[i].Importance > s[j].Importance
-}
-
 func replaceLinks(str string) string {
 	var re = regexp.MustCompile(`\[.*\](.*)`)
 	return re.ReplaceAllString(str, `$1.$2`)
@@ -0,0 +1,229 @@
+// Package worktree checks PRs out into git worktrees next to an existing
+// clone of the repo so they can be built and run locally. Every worktree
+// made is recorded in ~/.prty/worktrees.json so it can be removed once the
+// PR closes.
+package worktree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+	"github.com/inburst/prty/logger"
+)
+
+type Worktree struct {
+	PRID      string
+	Provider  string
+	OrgName   string
+	RepoName  string
+	Number    int
+	ClonePath string
+	Path      string
+	Branch    string
+	CreatedAt time.Time
+}
+
+// worktrees.json is read and written whole so calls are serialised
+var fileMutex sync.Mutex
+
+// Fetches the PR head and checks it out into its worktree, creating the
+// clone and worktree on first use. Returns the worktree path.
+func Checkout(c *config.Config, pr *datasource.PullRequest) (string, error) {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	clonePath, err := ensureClone(c.CheckoutRoots, pr)
+	if err != nil {
+		return "", err
+	}
+
+	// FETCH_HEAD is kept per worktree so the head goes into a ref that the
+	// clone and its worktrees share
+	ref := localRef(pr.Number)
+	if _, err := git(clonePath, "fetch", "origin", fmt.Sprintf("+%s:%s", pullRef(pr), ref)); err != nil {
+		return "", err
+	}
+
+	worktreesPath, err := config.GetWorktreesPath()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(worktreesPath, pr.OrgName, pr.RepoName, fmt.Sprintf("pr-%d", pr.Number))
+	branch := fmt.Sprintf("prty/pr-%d", pr.Number)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := git(clonePath, "worktree", "add", "-B", branch, path, ref); err != nil {
+			return "", err
+		}
+	} else {
+		// never throw away local work, a force push has to be resolved by hand
+		if _, err := git(path, "merge", "--ff-only", ref); err != nil {
+			return "", err
+		}
+	}
+
+	worktrees, err := load()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := worktrees[pr.ID]; !ok {
+		worktrees[pr.ID] = &Worktree{
+			PRID:      pr.ID,
+			Provider:  pr.Provider,
+			OrgName:   pr.OrgName,
+			RepoName:  pr.RepoName,
+			Number:    pr.Number,
+			ClonePath: clonePath,
+			Path:      path,
+			Branch:    branch,
+			CreatedAt: time.Now(),
+		}
+	}
+	return path, save(worktrees)
+}
+
+// Removes the worktree and branch made for the PR, if any. Worktrees with
+// uncommitted changes are left in place and stay tracked.
+func Remove(prID string) error {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	worktrees, err := load()
+	if err != nil {
+		return err
+	}
+	w, ok := worktrees[prID]
+	if !ok {
+		return nil
+	}
+	if _, err := os.Stat(w.Path); err == nil {
+		if _, err := git(w.ClonePath, "worktree", "remove", w.Path); err != nil {
+			return err
+		}
+	}
+	// the branch and ref may already be gone, they are only ours to clean up
+	if _, err := git(w.ClonePath, "branch", "-D", w.Branch); err != nil {
+		logger.Shared().Printf("worktree: %s\n", err)
+	}
+	if _, err := git(w.ClonePath, "update-ref", "-d", localRef(w.Number)); err != nil {
+		logger.Shared().Printf("worktree: %s\n", err)
+	}
+	delete(worktrees, prID)
+	return save(worktrees)
+}
+
+// Github exposes every PR head as refs/pull/N/head and gitlab as
+// refs/merge-requests/N/head, this works for forks too
+func pullRef(pr *datasource.PullRequest) string {
+	if pr.Provider == datasource.GitlabProviderName {
+		return fmt.Sprintf("refs/merge-requests/%d/head", pr.Number)
+	}
+	return fmt.Sprintf("refs/pull/%d/head", pr.Number)
+}
+
+// Where the fetched PR head is kept in the clone
+func localRef(number int) string {
+	return fmt.Sprintf("refs/prty/pr-%d", number)
+}
+
+// The repo's clone url derived from the PR's web url
+func cloneURL(pr *datasource.PullRequest) (string, error) {
+	for _, sep := range []string{"/-/merge_requests/", "/pull/"} {
+		if i := strings.LastIndex(pr.URL, sep); i > 0 {
+			return pr.URL[:i] + ".git", nil
+		}
+	}
+	return "", fmt.Errorf("unable to find the repo url for %s", pr.URL)
+}
+
+// Finds the repo under one of the roots or clones it into the first
+func ensureClone(roots []string, pr *datasource.PullRequest) (string, error) {
+	if len(roots) == 0 {
+		return "", errors.New("set CheckoutRoots in your config to check out PRs")
+	}
+	for _, root := range roots {
+		path := filepath.Join(expandHome(root), pr.OrgName, pr.RepoName)
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			return path, nil
+		}
+	}
+
+	url, err := cloneURL(pr)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(expandHome(roots[0]), pr.OrgName, pr.RepoName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if _, err := git(filepath.Dir(path), "clone", url, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// Runs git in dir and returns its output. Errors include git's message.
+func git(dir string, args ...string) (string, error) {
+	logger.Shared().Printf("worktree: git %s in %s\n", strings.Join(args, " "), dir)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// fail rather than hang on a credential prompt the TUI can not show
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func load() (map[string]*Worktree, error) {
+	worktrees := map[string]*Worktree{}
+	filePath, err := config.GetWorktreesFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return worktrees, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &worktrees); err != nil {
+		return nil, err
+	}
+	return worktrees, nil
+}
+
+func save(worktrees map[string]*Worktree) error {
+	filePath, err := config.GetWorktreesFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(worktrees, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
@@ -0,0 +1,32 @@
+package worktree
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/logger"
+)
+
+func TestWorktree(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Worktree Suite")
+}
+
+// worktrees.json, the worktrees and the log go to a throwaway home
+var home string
+
+var _ = BeforeSuite(func() {
+	var err error
+	home, err = ioutil.TempDir("", "prty-home")
+	Expect(err).NotTo(HaveOccurred())
+	os.Setenv("HOME", home)
+	Expect(logger.InitializeLogger()).To(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	os.RemoveAll(home)
+})
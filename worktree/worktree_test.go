@@ -0,0 +1,180 @@
+package worktree
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/inburst/prty/config"
+	"github.com/inburst/prty/datasource"
+)
+
+var _ = Describe("Worktree", func() {
+	githubPR := &datasource.PullRequest{
+		Provider: datasource.GithubProviderName,
+		OrgName:  "inburst",
+		RepoName: "prty",
+		Number:   12,
+		URL:      "https://github.com/inburst/prty/pull/12",
+	}
+	gitlabPR := &datasource.PullRequest{
+		Provider: datasource.GitlabProviderName,
+		OrgName:  "group/sub",
+		RepoName: "project",
+		Number:   7,
+		URL:      "https://gitlab.com/group/sub/project/-/merge_requests/7",
+	}
+
+	Context("pullRef", func() {
+		It("should use the head ref each provider keeps for a PR", func() {
+			Expect(pullRef(githubPR)).To(Equal("refs/pull/12/head"))
+			Expect(pullRef(gitlabPR)).To(Equal("refs/merge-requests/7/head"))
+		})
+	})
+
+	Context("cloneURL", func() {
+		It("should derive the repo url from the PR url", func() {
+			url, err := cloneURL(githubPR)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://github.com/inburst/prty.git"))
+
+			url, err = cloneURL(gitlabPR)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://gitlab.com/group/sub/project.git"))
+		})
+
+		It("should fail on an unknown url", func() {
+			_, err := cloneURL(&datasource.PullRequest{URL: "https://example.com"})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("ensureClone", func() {
+		var roots []string
+
+		BeforeEach(func() {
+			roots = []string{}
+			for i := 0; i < 2; i++ {
+				dir, err := ioutil.TempDir("", "prty-worktree")
+				Expect(err).To(BeNil())
+				roots = append(roots, dir)
+			}
+		})
+
+		AfterEach(func() {
+			for _, root := range roots {
+				os.RemoveAll(root)
+			}
+		})
+
+		It("should require a root", func() {
+			_, err := ensureClone([]string{}, githubPR)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("should find an existing clone under any root", func() {
+			clone := filepath.Join(roots[1], "inburst", "prty")
+			Expect(os.MkdirAll(filepath.Join(clone, ".git"), 0755)).To(Succeed())
+
+			path, err := ensureClone(roots, githubPR)
+			Expect(err).To(BeNil())
+			Expect(path).To(Equal(clone))
+		})
+	})
+
+	Context("Checkout", func() {
+		var (
+			root   string
+			origin string
+			c      *config.Config
+		)
+
+		pr := &datasource.PullRequest{
+			Provider: datasource.GithubProviderName,
+			ID:       "checkout",
+			OrgName:  "inburst",
+			RepoName: "prty",
+			Number:   12,
+			URL:      "https://github.com/inburst/prty/pull/12",
+		}
+
+		// runs git with an identity so commits work on a bare machine
+		run := func(dir string, args ...string) string {
+			args = append([]string{"-c", "user.name=prty", "-c", "user.email=prty@example.com"}, args...)
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+
+		// commits on top of the PR head and pushes it to the PR ref
+		pushToPR := func(message string) string {
+			work, err := ioutil.TempDir(root, "work")
+			Expect(err).NotTo(HaveOccurred())
+			run(work, "clone", "-q", origin, ".")
+			run(work, "fetch", "-q", "origin", "refs/pull/12/head")
+			run(work, "checkout", "-q", "FETCH_HEAD")
+			run(work, "commit", "-q", "--allow-empty", "-m", message)
+			run(work, "push", "-q", "origin", "HEAD:refs/pull/12/head")
+			return run(work, "rev-parse", "HEAD")
+		}
+
+		BeforeEach(func() {
+			var err error
+			root, err = ioutil.TempDir("", "prty-checkout")
+			Expect(err).NotTo(HaveOccurred())
+
+			origin = filepath.Join(root, "origin.git")
+			run(root, "init", "-q", "--bare", origin)
+			seed := filepath.Join(root, "seed")
+			run(root, "init", "-q", seed)
+			run(seed, "commit", "-q", "--allow-empty", "-m", "initial")
+			run(seed, "push", "-q", origin, "HEAD:refs/heads/main", "HEAD:refs/pull/12/head")
+
+			// the PR's repo is already cloned under the checkout root
+			c = &config.Config{CheckoutRoots: []string{filepath.Join(root, "checkouts")}}
+			run(root, "clone", "-q", origin, filepath.Join(root, "checkouts", "inburst", "prty"))
+		})
+
+		AfterEach(func() {
+			// worktrees live under the shared home, not the root
+			Remove(pr.ID)
+			os.RemoveAll(root)
+		})
+
+		It("should create the worktree then fast forward it to new commits", func() {
+			first := pushToPR("first")
+			path, err := Checkout(c, pr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(run(path, "rev-parse", "HEAD")).To(Equal(first))
+
+			second := pushToPR("second")
+			again, err := Checkout(c, pr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(again).To(Equal(path))
+			Expect(run(path, "rev-parse", "HEAD")).To(Equal(second))
+		})
+
+		It("should remove the worktree, its branch and ref", func() {
+			path, err := Checkout(c, pr)
+			Expect(err).NotTo(HaveOccurred())
+			clone := filepath.Join(root, "checkouts", "inburst", "prty")
+
+			Expect(Remove(pr.ID)).To(Succeed())
+			_, err = os.Stat(path)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(run(clone, "branch", "--list", "prty/pr-12")).To(BeEmpty())
+			Expect(run(clone, "for-each-ref", "refs/prty")).To(BeEmpty())
+
+			worktrees, err := load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(worktrees).NotTo(HaveKey(pr.ID))
+		})
+	})
+})